@@ -2,17 +2,23 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/PedroM2626/PriceWatcher/internal/alerts"
 	"github.com/PedroM2626/PriceWatcher/internal/config"
 	"github.com/PedroM2626/PriceWatcher/internal/scraper"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
 	"github.com/PedroM2626/PriceWatcher/internal/storage"
 )
 
 func main() {
+	reextract := flag.Bool("reextract", false, "re-run extraction on cached pages and exit")
+	flag.Parse()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -33,17 +39,34 @@ func main() {
 	}
 	defer db.Close()
 
+	// Load per-site extraction rules
+	rules, err := siterules.LoadDir(cfg.Scraper.RulesDir)
+	if err != nil {
+		log.Fatalf("Failed to load site rules: %v", err)
+	}
+
+	scraperCfg, err := scraper.ConfigFrom(cfg.Scraper, rules)
+	if err != nil {
+		log.Fatalf("Failed to configure scraper: %v", err)
+	}
+
 	// Initialize scraper service
-	s := scraper.NewScraper(db, scraper.ScraperConfig{
-		UserAgent:      cfg.Scraper.UserAgent,
-		RequestDelay:   cfg.Scraper.RequestDelay,
-		RequestTimeout: cfg.Scraper.RequestTimeout,
-		Workers:        cfg.Scraper.Workers,
-	})
-
-	// Start scraper in background (no-op for now)
+	s := scraper.NewScraper(db, scraperCfg)
+
+	if *reextract {
+		n, err := s.ReextractAll(ctx)
+		if err != nil {
+			log.Fatalf("Re-extraction failed: %v", err)
+		}
+		log.Printf("Re-extracted %d products from cached pages", n)
+		return
+	}
+
+	// Check due products in the background, evaluating their alerts
+	done := make(chan struct{})
 	go func() {
-		if err := s.Run(); err != nil {
+		defer close(done)
+		if err := s.Run(ctx, alerts.NewEvaluator(db).HandleCheck); err != nil {
 			log.Printf("Scraper error: %v", err)
 		}
 	}()
@@ -52,5 +75,6 @@ func main() {
 
 	<-sigChan
 	log.Println("Shutting down PriceWatcher...")
-	_ = ctx
+	cancel()
+	<-done
 }
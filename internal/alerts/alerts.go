@@ -0,0 +1,171 @@
+// Package alerts decides which alerts a product check triggers.
+package alerts
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/PedroM2626/PriceWatcher/internal/analysis"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/scraper"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+// Evaluator checks the alerts of products as they are checked
+type Evaluator struct {
+	storage storage.Storage
+}
+
+// NewEvaluator creates an evaluator reading and claiming alerts in storage
+func NewEvaluator(storage storage.Storage) *Evaluator {
+	return &Evaluator{storage: storage}
+}
+
+// HandleCheck triggers the alerts a product's check calls for; it is the
+// scraper.CheckFunc of Run and ProcessDue. The scraper has already merged the
+// result into the product, recorded it in the price history, logged the run
+// and scheduled the next check.
+func (e *Evaluator) HandleCheck(ctx context.Context, product *models.Product, change *scraper.Change) {
+	// Trigger price alerts if price or shipping changed
+	if change.PriceChanged {
+		e.checkPriceAlerts(ctx, change, product)
+	}
+	if models.BackInStock(change.Old.Availability, product.Availability) {
+		e.checkStockAlerts(ctx, change, product)
+	}
+}
+
+// checkPriceAlerts checks if any price alerts should be triggered
+func (e *Evaluator) checkPriceAlerts(ctx context.Context, change *scraper.Change, newProduct *models.Product) {
+	oldProduct := &change.Old
+
+	// Get all active alerts for this product
+	alerts, err := e.storage.GetActiveAlertsForProduct(ctx, newProduct.ID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("product_id", newProduct.ID.String()).
+			Msg("Failed to get alerts for product")
+		return
+	}
+
+	var discount *analysis.DiscountReport
+	for _, alert := range alerts {
+		if alert.Kind == models.AlertKindBackInStock {
+			continue
+		}
+
+		// Check if the price is below the target price, shipping included
+		// when the user asked for it
+		price, oldPrice := newProduct.CurrentPrice, oldProduct.CurrentPrice
+		if alert.UseTotalPrice {
+			price, oldPrice = newProduct.TotalPrice(), oldProduct.TotalPrice()
+		}
+		if price <= alert.TargetPrice {
+			if alert.SkipFakeDiscount && newProduct.DiscountPercent > 0 {
+				if discount == nil {
+					discount = e.checkDiscount(ctx, newProduct)
+				}
+				if discount.Inflated {
+					log.Info().
+						Str("alert_id", alert.ID.String()).
+						Str("product_id", newProduct.ID.String()).
+						Str("reason", discount.Reason).
+						Msg("Skipping alert on inflated discount")
+					continue
+				}
+			}
+
+			e.fireAlert(ctx, change, alert, newProduct, oldPrice)
+		}
+	}
+}
+
+// checkStockAlerts fires the back-in-stock alerts of a product that is in
+// stock again
+func (e *Evaluator) checkStockAlerts(ctx context.Context, change *scraper.Change, newProduct *models.Product) {
+	alerts, err := e.storage.GetActiveAlertsForProduct(ctx, newProduct.ID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("product_id", newProduct.ID.String()).
+			Msg("Failed to get alerts for product")
+		return
+	}
+
+	for _, alert := range alerts {
+		if alert.Kind != models.AlertKindBackInStock {
+			continue
+		}
+		e.fireAlert(ctx, change, alert, newProduct, change.Old.CurrentPrice)
+	}
+}
+
+// fireAlert marks the alert notified and triggers it, unless another
+// instance already did for an overlapping check of the same product. The
+// alert is claimed before it is sent: a crash in between loses one
+// notification rather than duplicating it.
+func (e *Evaluator) fireAlert(ctx context.Context, change *scraper.Change, alert *models.Alert, product *models.Product, oldPrice float64) {
+	now := time.Now()
+	since := change.Started
+	if since.IsZero() {
+		since = now
+	}
+	claimed, err := e.storage.ClaimAlert(ctx, alert.ID, since, now)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("alert_id", alert.ID.String()).
+			Msg("Failed to claim alert")
+		return
+	}
+	if !claimed {
+		log.Debug().
+			Str("alert_id", alert.ID.String()).
+			Msg("Alert already notified by another worker")
+		return
+	}
+	alert.NotifiedAt = now
+
+	if err := e.triggerAlert(ctx, alert, product, oldPrice); err != nil {
+		log.Error().
+			Err(err).
+			Str("alert_id", alert.ID.String()).
+			Msg("Failed to trigger alert")
+	}
+}
+
+// checkDiscount evaluates the product's advertised discount, treating it as
+// genuine when the history can't be loaded
+func (e *Evaluator) checkDiscount(ctx context.Context, product *models.Product) *analysis.DiscountReport {
+	report, err := analysis.CheckDiscount(ctx, e.storage, product, analysis.DiscountConfig{})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("product_id", product.ID.String()).
+			Msg("Failed to check discount")
+		return &analysis.DiscountReport{}
+	}
+	return report
+}
+
+// triggerAlert triggers a price or back-in-stock alert
+func (e *Evaluator) triggerAlert(ctx context.Context, alert *models.Alert, product *models.Product, oldPrice float64) error {
+	// TODO: Implement alert triggering logic
+	// This would use the notifier package to send notifications
+	// based on the alert's notification type (email, telegram, etc.)
+
+	log.Info().
+		Str("alert_id", alert.ID.String()).
+		Str("product_id", product.ID.String()).
+		Float64("target_price", alert.TargetPrice).
+		Float64("current_price", product.CurrentPrice).
+		Float64("total_price", product.TotalPrice()).
+		Bool("use_total_price", alert.UseTotalPrice).
+		Str("kind", alert.Kind).
+		Str("availability", string(product.Availability)).
+		Msg("Alert triggered")
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package alerts
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/scraper"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+func TestHandleCheck(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		alert    models.Alert
+		oldPrice float64
+		price    float64
+		shipping float64
+		discount float64 // advertised, against a steady 800 history
+		from, to models.Availability
+		fired    bool
+	}{
+		{name: "price drops below target", alert: models.Alert{TargetPrice: 750}, oldPrice: 800, price: 700, fired: true},
+		{name: "price drops to target", alert: models.Alert{TargetPrice: 700}, oldPrice: 800, price: 700, fired: true},
+		{name: "price drops above target", alert: models.Alert{TargetPrice: 650}, oldPrice: 800, price: 700},
+		{name: "price unchanged below target", alert: models.Alert{TargetPrice: 750}, oldPrice: 700, price: 700},
+		{name: "shipping counted", alert: models.Alert{TargetPrice: 750, UseTotalPrice: true}, oldPrice: 800, price: 700, shipping: 60},
+		{name: "genuine discount", alert: models.Alert{TargetPrice: 750, SkipFakeDiscount: true}, oldPrice: 800, price: 700, discount: 12, fired: true},
+		{name: "inflated discount skipped", alert: models.Alert{TargetPrice: 750, SkipFakeDiscount: true}, oldPrice: 800, price: 700, discount: 50},
+		{
+			name:     "back in stock",
+			alert:    models.Alert{Kind: models.AlertKindBackInStock},
+			oldPrice: 700,
+			price:    700,
+			from:     models.AvailabilityOutOfStock,
+			to:       models.AvailabilityInStock,
+			fired:    true,
+		},
+		{
+			name:     "back in stock alert ignores price drops",
+			alert:    models.Alert{Kind: models.AlertKindBackInStock, TargetPrice: 750},
+			oldPrice: 800,
+			price:    700,
+		},
+		{
+			name:     "price alert ignores restocks",
+			alert:    models.Alert{TargetPrice: 650},
+			oldPrice: 700,
+			price:    700,
+			from:     models.AvailabilityOutOfStock,
+			to:       models.AvailabilityInStock,
+		},
+	}
+
+	for _, tt := range tests {
+		store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		old := models.Product{Name: "Fone XYZ", URL: "https://loja.example/fone-xyz", Currency: "BRL", CurrentPrice: tt.oldPrice}
+		old.SetAvailability(models.AvailabilityInStock)
+		if tt.from != "" {
+			old.SetAvailability(tt.from)
+		}
+		if err := store.CreateProduct(ctx, &old); err != nil {
+			t.Fatal(err)
+		}
+		history := &models.PriceHistory{ProductID: old.ID, Price: 800, CreatedAt: time.Now().Add(-91 * 24 * time.Hour).Add(time.Hour)}
+		if err := store.AddPriceHistory(ctx, history); err != nil {
+			t.Fatal(err)
+		}
+		alert := tt.alert
+		alert.ProductID, alert.IsActive, alert.NotificationType = old.ID, true, "email"
+		if err := store.CreateAlert(ctx, &alert); err != nil {
+			t.Fatal(err)
+		}
+
+		product := old
+		product.CurrentPrice, product.ShippingCost, product.DiscountPercent = tt.price, tt.shipping, tt.discount
+		if tt.to != "" {
+			product.SetAvailability(tt.to)
+		}
+		change := &scraper.Change{
+			Old:          old,
+			PriceChanged: product.TotalPrice() != old.TotalPrice(),
+			StockChanged: product.Availability != old.Availability,
+			Started:      time.Now(),
+		}
+		NewEvaluator(store).HandleCheck(ctx, &product, change)
+
+		got, err := store.GetAlertByID(ctx, alert.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fired := !got.NotifiedAt.IsZero(); fired != tt.fired {
+			t.Errorf("%s: fired = %v, want %v", tt.name, fired, tt.fired)
+		}
+		store.Close()
+	}
+}
@@ -0,0 +1,179 @@
+// Package analysis derives insights from recorded price history.
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+// DiscountConfig tunes fake-discount detection
+type DiscountConfig struct {
+	Windows     []int   // lookback windows in days; defaults to 30, 60 and 90
+	MinGap      float64 // percentage points the advertised discount may exceed the real one by; defaults to 10
+	MinCoverage float64 // share of a window that must have a known price for it to count; defaults to 0.5
+}
+
+var defaultWindows = []int{30, 60, 90}
+
+const (
+	defaultMinGap      = 10
+	defaultMinCoverage = 0.5
+)
+
+// WindowMedian compares the current price with the usual price over one window
+type WindowMedian struct {
+	Days         int     `json:"days"`
+	Median       float64 `json:"median"`        // time-weighted median price, 0 without enough history
+	Coverage     float64 `json:"coverage"`      // share of the window with a known price
+	RealDiscount float64 `json:"real_discount"` // current price against the median, in percent
+	Inflated     bool    `json:"inflated"`
+}
+
+// DiscountReport tells whether a product's advertised discount holds up
+// against its own price history
+type DiscountReport struct {
+	ProductID          string         `json:"product_id"`
+	Price              float64        `json:"price"`
+	ListPrice          float64        `json:"list_price"`
+	AdvertisedDiscount float64        `json:"advertised_discount"`
+	Windows            []WindowMedian `json:"windows"`
+	// Inflated is set when the advertised discount exceeds the real one by
+	// MinGap in every window with enough history, e.g. when the price was
+	// raised shortly before a "50% off" sale
+	Inflated bool   `json:"inflated"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// CheckDiscount loads the price history of product and evaluates its
+// advertised discount
+func CheckDiscount(ctx context.Context, store storage.Storage, product *models.Product, cfg DiscountConfig) (*DiscountReport, error) {
+	cfg = cfg.withDefaults()
+	longest := 0
+	for _, days := range cfg.Windows {
+		if days > longest {
+			longest = days
+		}
+	}
+	// Every check is recorded, so an extra day of history normally includes
+	// the check in effect when the longest window opens. If checks were further
+	// apart, the uncounted start of the window only lowers its coverage.
+	history, err := store.GetPriceHistory(ctx, product.ID, longest+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price history: %w", err)
+	}
+	return EvaluateDiscount(product, history, time.Now(), cfg), nil
+}
+
+// EvaluateDiscount checks the advertised discount of product against history
+// (in any order), as of now
+func EvaluateDiscount(product *models.Product, history []*models.PriceHistory, now time.Time, cfg DiscountConfig) *DiscountReport {
+	cfg = cfg.withDefaults()
+	report := &DiscountReport{
+		ProductID:          product.ID.String(),
+		Price:              product.CurrentPrice,
+		ListPrice:          product.ListPrice,
+		AdvertisedDiscount: product.DiscountPercent,
+	}
+	if report.AdvertisedDiscount <= 0 || report.Price <= 0 {
+		report.Reason = "no advertised discount"
+		return report
+	}
+
+	sorted := make([]*models.PriceHistory, len(history))
+	copy(sorted, history)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	counted, inflated := 0, 0
+	best := 0.0 // most generous real discount among the counted windows
+	for _, days := range cfg.Windows {
+		w := WindowMedian{Days: days}
+		w.Median, w.Coverage = weightedMedian(sorted, now.AddDate(0, 0, -days), now)
+		if w.Median > 0 && w.Coverage >= cfg.MinCoverage {
+			w.RealDiscount = price.Discount(w.Median, report.Price)
+			w.Inflated = report.AdvertisedDiscount-w.RealDiscount >= cfg.MinGap
+			if counted == 0 || w.RealDiscount > best {
+				best = w.RealDiscount
+			}
+			counted++
+			if w.Inflated {
+				inflated++
+			}
+		}
+		report.Windows = append(report.Windows, w)
+	}
+
+	switch {
+	case counted == 0:
+		report.Reason = "not enough price history"
+	case inflated == counted:
+		report.Inflated = true
+		report.Reason = fmt.Sprintf("advertised %.0f%% off, but the price is only %.1f%% below its usual level",
+			report.AdvertisedDiscount, best)
+	}
+	return report
+}
+
+// withDefaults fills in unset fields
+func (c DiscountConfig) withDefaults() DiscountConfig {
+	if len(c.Windows) == 0 {
+		c.Windows = defaultWindows
+	}
+	if c.MinGap <= 0 {
+		c.MinGap = defaultMinGap
+	}
+	if c.MinCoverage <= 0 {
+		c.MinCoverage = defaultMinCoverage
+	}
+	return c
+}
+
+// weightedMedian returns the median of the prices in effect between from and
+// to, each weighted by how long it lasted, and the share of the window for
+// which a price is known. history must be sorted oldest first; each entry's
+// price holds until the next one.
+func weightedMedian(history []*models.PriceHistory, from, to time.Time) (float64, float64) {
+	type span struct {
+		price float64
+		d     time.Duration
+	}
+	var spans []span
+	var known time.Duration
+	for i, h := range history {
+		start, end := h.CreatedAt, to
+		if i+1 < len(history) {
+			end = history[i+1].CreatedAt
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) || h.Price <= 0 {
+			continue
+		}
+		spans = append(spans, span{h.Price, end.Sub(start)})
+		known += end.Sub(start)
+	}
+	if known == 0 {
+		return 0, 0
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].price < spans[j].price })
+	var acc time.Duration
+	median := spans[len(spans)-1].price
+	for _, s := range spans {
+		acc += s.d
+		if acc*2 >= known {
+			median = s.price
+			break
+		}
+	}
+	return median, float64(known) / float64(to.Sub(from))
+}
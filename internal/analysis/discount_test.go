@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+func TestWeightedMedian(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	at := func(daysAgo int, price float64) *models.PriceHistory {
+		return &models.PriceHistory{Price: price, CreatedAt: now.AddDate(0, 0, -daysAgo)}
+	}
+
+	tests := []struct {
+		name     string
+		history  []*models.PriceHistory // oldest first
+		days     int
+		median   float64
+		coverage float64
+	}{
+		{name: "empty", days: 30},
+		{name: "price set before the window", history: []*models.PriceHistory{at(100, 800)}, days: 30, median: 800, coverage: 1},
+		{name: "price set inside the window", history: []*models.PriceHistory{at(15, 800)}, days: 30, median: 800, coverage: 0.5},
+		{name: "longest lasting price wins", history: []*models.PriceHistory{at(40, 1000), at(20, 700), at(10, 1000)}, days: 30, median: 1000, coverage: 1},
+		{name: "short spike is ignored", history: []*models.PriceHistory{at(60, 500), at(3, 900)}, days: 30, median: 500, coverage: 1},
+		{name: "even split takes the lower price", history: []*models.PriceHistory{at(60, 1000), at(30, 700)}, days: 60, median: 700, coverage: 1},
+		{name: "zero prices are unknown", history: []*models.PriceHistory{at(30, 0), at(15, 600)}, days: 30, median: 600, coverage: 0.5},
+	}
+
+	for _, tt := range tests {
+		median, coverage := weightedMedian(tt.history, now.AddDate(0, 0, -tt.days), now)
+		if median != tt.median || coverage != tt.coverage {
+			t.Errorf("%s: weightedMedian = %v, %v, want %v, %v", tt.name, median, coverage, tt.median, tt.coverage)
+		}
+	}
+}
+
+func TestEvaluateDiscount(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	at := func(daysAgo int, price float64) *models.PriceHistory {
+		return &models.PriceHistory{Price: price, CreatedAt: now.AddDate(0, 0, -daysAgo)}
+	}
+
+	tests := []struct {
+		name       string
+		price      float64
+		advertised float64
+		history    []*models.PriceHistory // in any order
+		medians    []float64              // per 30/60/90-day window
+		inflated   bool
+		reason     string // substring of Reason, "" for none
+	}{
+		{
+			name:    "no advertised discount",
+			price:   700,
+			history: []*models.PriceHistory{at(100, 800)},
+			reason:  "no advertised discount",
+		},
+		{
+			name:       "empty history",
+			price:      700,
+			advertised: 50,
+			medians:    []float64{0, 0, 0},
+			reason:     "not enough price history",
+		},
+		{
+			name:       "history too short for any window",
+			price:      700,
+			advertised: 50,
+			history:    []*models.PriceHistory{at(10, 800)},
+			medians:    []float64{800, 800, 800},
+			reason:     "not enough price history",
+		},
+		{
+			name:       "genuine discount",
+			price:      700,
+			advertised: 30,
+			history:    []*models.PriceHistory{at(1, 700), at(100, 1000)},
+			medians:    []float64{1000, 1000, 1000},
+		},
+		{
+			name:       "price raised before the sale",
+			price:      700,
+			advertised: 50,
+			history:    []*models.PriceHistory{at(1, 700), at(7, 1400), at(100, 800)},
+			medians:    []float64{800, 800, 800},
+			inflated:   true,
+			reason:     "only 12.5% below",
+		},
+		{
+			name:       "gap exactly at the minimum is inflated",
+			price:      700,
+			advertised: 22.5,
+			history:    []*models.PriceHistory{at(100, 800)},
+			medians:    []float64{800, 800, 800},
+			inflated:   true,
+		},
+		{
+			name:       "gap just under the minimum is genuine",
+			price:      700,
+			advertised: 22.4,
+			history:    []*models.PriceHistory{at(100, 800)},
+			medians:    []float64{800, 800, 800},
+		},
+		{
+			name:       "genuine against the 90-day median",
+			price:      700,
+			advertised: 30,
+			history:    []*models.PriceHistory{at(100, 1000), at(30, 700)},
+			medians:    []float64{700, 700, 1000},
+		},
+		{
+			name:       "windows without enough coverage are left out",
+			price:      700,
+			advertised: 50,
+			history:    []*models.PriceHistory{at(20, 800)},
+			medians:    []float64{800, 800, 800},
+			inflated:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		product := &models.Product{CurrentPrice: tt.price, DiscountPercent: tt.advertised}
+		report := EvaluateDiscount(product, tt.history, now, DiscountConfig{})
+
+		if report.Inflated != tt.inflated {
+			t.Errorf("%s: Inflated = %v, want %v (%+v)", tt.name, report.Inflated, tt.inflated, report)
+		}
+		if tt.reason == "" && !tt.inflated && report.Reason != "" {
+			t.Errorf("%s: Reason = %q, want none", tt.name, report.Reason)
+		}
+		if !strings.Contains(report.Reason, tt.reason) {
+			t.Errorf("%s: Reason = %q, want it to mention %q", tt.name, report.Reason, tt.reason)
+		}
+		if len(report.Windows) != len(tt.medians) {
+			t.Errorf("%s: %d windows, want %d", tt.name, len(report.Windows), len(tt.medians))
+			continue
+		}
+		for i, w := range report.Windows {
+			if w.Days != defaultWindows[i] || w.Median != tt.medians[i] {
+				t.Errorf("%s: window %d = %d days, median %v, want %d days, median %v",
+					tt.name, i, w.Days, w.Median, defaultWindows[i], tt.medians[i])
+			}
+		}
+	}
+}
+
+func TestCheckDiscount(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	product := &models.Product{Name: "Fone XYZ", URL: "https://loja.example/fone-xyz", Currency: "BRL", CurrentPrice: 700, DiscountPercent: 50}
+	if err := store.CreateProduct(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	// Every check is recorded; the one before the 90-day window opened sets
+	// its starting price, and older ones are not needed
+	now := time.Now()
+	checks := []*models.PriceHistory{
+		{Price: 2000, CreatedAt: now.AddDate(0, 0, -120)},
+		{Price: 800, CreatedAt: now.Add(-90*24*time.Hour - 12*time.Hour)},
+		{Price: 800, CreatedAt: now.AddDate(0, 0, -60)},
+		{Price: 800, CreatedAt: now.AddDate(0, 0, -30)},
+		{Price: 800, CreatedAt: now.AddDate(0, 0, -1)},
+	}
+	for _, entry := range checks {
+		entry.ProductID = product.ID
+		if err := store.AddPriceHistory(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := CheckDiscount(ctx, store, product, DiscountConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Inflated {
+		t.Errorf("report = %+v, want an inflated discount against the 800 baseline", report)
+	}
+	for _, w := range report.Windows {
+		if w.Median != 800 || w.Coverage != 1 {
+			t.Errorf("%d-day window: median %v, coverage %v, want 800, 1", w.Days, w.Median, w.Coverage)
+		}
+	}
+}
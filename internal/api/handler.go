@@ -2,17 +2,29 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 	"github.com/google/uuid"
+	"github.com/PedroM2626/PriceWatcher/internal/analysis"
 	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/schedule"
+	"github.com/PedroM2626/PriceWatcher/internal/scraper"
 	"github.com/PedroM2626/PriceWatcher/internal/storage"
 )
 
+// ScraperStats reports scraping health; implemented by *scraper.PriceScraper
+type ScraperStats interface {
+	BlockStats() []scraper.HostBlockStats
+}
+
 // Handler handles HTTP requests
 type Handler struct {
 	storage storage.Storage
+	stats   ScraperStats // nil when no scraper runs in this process
 }
 
 // NewHandler creates a new handler instance
@@ -43,6 +55,9 @@ func (h *Handler) RegisterRoutes(router *gin.Engine) {
 				products.GET(":id", h.getProduct)
 				products.PUT(":id", h.updateProduct)
 				products.DELETE(":id", h.deleteProduct)
+				products.GET(":id/offers", h.listOffers)
+				products.GET(":id/discount", h.checkDiscount)
+				products.GET(":id/runs", h.listScrapeRuns)
 			}
 
 			alerts := protected.Group("/alerts")
@@ -53,6 +68,18 @@ func (h *Handler) RegisterRoutes(router *gin.Engine) {
 				alerts.PUT(":id", h.updateAlert)
 				alerts.DELETE(":id", h.deleteAlert)
 			}
+
+			schedules := protected.Group("/schedules")
+			{
+				schedules.GET("", h.listSiteSchedules)
+				schedules.PUT(":host", h.setSiteSchedule)
+				schedules.DELETE(":host", h.deleteSiteSchedule)
+			}
+
+			scraping := protected.Group("/scraper")
+			{
+				scraping.GET("/blocks", h.blockStats)
+			}
 		}
 	}
 }
@@ -66,6 +93,9 @@ func (h *Handler) authMiddleware() gin.HandlerFunc {
 }
 
 // Product handlers
+
+// listProducts returns the tracked products; with ?fake_discount=true only
+// those whose advertised discount their price history doesn't back
 func (h *Handler) listProducts(c *gin.Context) {
 	products, err := h.storage.ListProducts(c.Request.Context(), 100, 0)
 	if err != nil {
@@ -73,6 +103,24 @@ func (h *Handler) listProducts(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list products"})
 		return
 	}
+	if fake, _ := strconv.ParseBool(c.Query("fake_discount")); fake {
+		flagged := make([]*models.Product, 0)
+		for _, p := range products {
+			if p.DiscountPercent <= 0 {
+				continue
+			}
+			report, err := analysis.CheckDiscount(c.Request.Context(), h.storage, p, analysis.DiscountConfig{})
+			if err != nil {
+				log.Error().Err(err).Str("product_id", p.ID.String()).Msg("Failed to check discount")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check discounts"})
+				return
+			}
+			if report.Inflated {
+				flagged = append(flagged, p)
+			}
+		}
+		products = flagged
+	}
 	c.JSON(http.StatusOK, gin.H{"items": products})
 }
 
@@ -83,6 +131,8 @@ func (h *Handler) createProduct(c *gin.Context) {
 		return
 	}
 	if product.ID == uuid.Nil { product.ID = uuid.New() }
+	if !validSchedule(c, product.Schedule) { return }
+	product.NextCheckAt = time.Time{}
 	if err := h.storage.CreateProduct(c.Request.Context(), &product); err != nil {
 		log.Error().Err(err).Msg("Failed to create product")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
@@ -109,6 +159,83 @@ func (h *Handler) getProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, product)
 }
 
+// listOffers returns the sellers found at the last check, or every recorded
+// offer of the last ?days=N days
+func (h *Handler) listOffers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var offers []*models.Offer
+	if days := c.Query("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+			return
+		}
+		offers, err = h.storage.GetOffers(c.Request.Context(), id, n)
+	} else {
+		offers, err = h.storage.GetLatestOffers(c.Request.Context(), id)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("product_id", id.String()).Msg("Failed to list offers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list offers"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": offers})
+}
+
+// listScrapeRuns returns the latest checks of a product, newest first;
+// ?limit=N caps how many (50 by default)
+func (h *Handler) listScrapeRuns(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+	runs, err := h.storage.GetScrapeRuns(c.Request.Context(), id, limit)
+	if err != nil {
+		log.Error().Err(err).Str("product_id", id.String()).Msg("Failed to list scrape runs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scrape runs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": runs})
+}
+
+// checkDiscount compares the product's advertised discount with its 30, 60
+// and 90-day median price
+func (h *Handler) checkDiscount(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	product, err := h.storage.GetProductByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get product"})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	report, err := analysis.CheckDiscount(c.Request.Context(), h.storage, product, analysis.DiscountConfig{})
+	if err != nil {
+		log.Error().Err(err).Str("product_id", id.String()).Msg("Failed to check discount")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check discount"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
 func (h *Handler) updateProduct(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -121,10 +248,34 @@ func (h *Handler) updateProduct(c *gin.Context) {
 		return
 	}
 	product.ID = id
+	if !validSchedule(c, product.Schedule) { return }
+	old, err := h.storage.GetProductByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get product"})
+		return
+	}
+	if old == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	// The cache validators aren't part of the JSON body and the check status
+	// belongs to the scraper; keep them rather than blanking them
+	product.ETag = old.ETag
+	product.LastModified = old.LastModified
+	product.ScrapeStatus = old.ScrapeStatus
 	if err := h.storage.UpdateProduct(c.Request.Context(), &product); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 		return
 	}
+	// The next check was planned with the old schedule; make the product due
+	// so the new one takes over from its next check
+	product.NextCheckAt = old.NextCheckAt
+	if product.Schedule != old.Schedule {
+		if err := h.storage.SetNextCheck(c.Request.Context(), id, time.Time{}); err != nil {
+			log.Error().Err(err).Str("product_id", id.String()).Msg("Failed to reschedule product")
+		}
+		product.NextCheckAt = time.Time{}
+	}
 	c.JSON(http.StatusOK, product)
 }
 
@@ -159,6 +310,7 @@ func (h *Handler) createAlert(c *gin.Context) {
 		return
 	}
 	if alert.ID == uuid.Nil { alert.ID = uuid.New() }
+	if !validAlertKind(c, alert.Kind) { return }
 	if err := h.storage.CreateAlert(c.Request.Context(), &alert); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert"})
 		return
@@ -196,6 +348,7 @@ func (h *Handler) updateAlert(c *gin.Context) {
 		return
 	}
 	alert.ID = id
+	if !validAlertKind(c, alert.Kind) { return }
 	if err := h.storage.UpdateAlert(c.Request.Context(), &alert); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
 		return
@@ -216,6 +369,83 @@ func (h *Handler) deleteAlert(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// Site schedule handlers
+
+// listSiteSchedules returns the default check schedule of each store that has one
+func (h *Handler) listSiteSchedules(c *gin.Context) {
+	schedules, err := h.storage.ListSiteSchedules(c.Request.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list site schedules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list site schedules"})
+		return
+	}
+	if schedules == nil { schedules = []*models.SiteSchedule{} }
+	c.JSON(http.StatusOK, gin.H{"items": schedules})
+}
+
+// setSiteSchedule sets the schedule used by the store's products that don't
+// have their own. It applies from each product's next check.
+func (h *Handler) setSiteSchedule(c *gin.Context) {
+	var body struct {
+		Schedule string `json:"schedule" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validSchedule(c, body.Schedule) { return }
+	sched := &models.SiteSchedule{Host: strings.ToLower(c.Param("host")), Schedule: body.Schedule}
+	if err := h.storage.SetSiteSchedule(c.Request.Context(), sched); err != nil {
+		log.Error().Err(err).Str("host", sched.Host).Msg("Failed to set site schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set site schedule"})
+		return
+	}
+	c.JSON(http.StatusOK, sched)
+}
+
+func (h *Handler) deleteSiteSchedule(c *gin.Context) {
+	if err := h.storage.DeleteSiteSchedule(c.Request.Context(), strings.ToLower(c.Param("host"))); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete site schedule"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// validSchedule reports whether spec is empty or a valid schedule, answering
+// with 400 when it isn't
+func validSchedule(c *gin.Context, spec string) bool {
+	if spec == "" {
+		return true
+	}
+	if _, err := schedule.Parse(spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// validAlertKind reports whether kind is empty (a price alert) or a known
+// alert kind, answering with 400 when it isn't
+func validAlertKind(c *gin.Context, kind string) bool {
+	switch kind {
+	case "", models.AlertKindPrice, models.AlertKindBackInStock:
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alert kind"})
+	return false
+}
+
+// Scraper handlers
+
+// blockStats returns the per-store block page rates
+func (h *Handler) blockStats(c *gin.Context) {
+	if h.stats == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scraper is not running in this process"})
+		return
+	}
+	c.JSON(http.StatusOK, h.stats.BlockStats())
+}
+
 // Auth handlers (basic 501 placeholders)
 func (h *Handler) register(c *gin.Context)    { c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"}) }
 func (h *Handler) login(c *gin.Context)       { c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"}) }
@@ -65,6 +65,12 @@ func NewServer(cfg Config, storage storage.Storage) *Server {
 	}
 }
 
+// SetScraperStats exposes the statistics of the scraper running alongside the
+// server. It must be called before Start.
+func (s *Server) SetScraperStats(stats ScraperStats) {
+	s.handler.stats = stats
+}
+
 // Start starts the API server
 func (s *Server) Start() error {
 	log.Info().Str("address", s.httpServer.Addr).Msg("Starting API server")
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,27 +15,225 @@ type Product struct {
 	ImageURL     string    `json:"image_url" db:"image_url"`
 	CurrentPrice float64   `json:"current_price" db:"current_price"`
 	Currency     string    `json:"currency" db:"currency"`
-	IsAvailable  bool      `json:"is_available" db:"is_available"`
+	IsAvailable  bool      `json:"is_available" db:"is_available"` // the product can be ordered, see Availability.Purchasable
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 	Website      string    `json:"website" db:"website"`
+	PriceSource  string    `json:"price_source" db:"price_source"`   // extraction strategy that produced CurrentPrice
+	Fetcher      string    `json:"fetcher,omitempty" db:"fetcher"`   // how the page is downloaded; empty uses the site/default setting
+	ScrapeStatus string    `json:"scrape_status" db:"scrape_status"` // outcome of the last check, one of the ScrapeStatus* values
+	ETag         string    `json:"-" db:"etag"`                      // validators sent back on the next check
+	LastModified string    `json:"-" db:"last_modified"`
+	ShippingCost float64   `json:"shipping_cost" db:"shipping_cost"` // 0 when free or not shown
+	FreeShipping bool      `json:"free_shipping" db:"free_shipping"` // the page advertises free shipping
+
+	// Availability is the stock state found at the last check; changes are
+	// recorded in the price history
+	Availability Availability `json:"availability" db:"availability"`
+
+	// ListPrice is the struck-through "de R$ X" price and DiscountPercent the
+	// discount the store advertises; both are the store's claims, 0 when absent
+	ListPrice       float64 `json:"list_price,omitempty" db:"list_price"`
+	DiscountPercent float64 `json:"discount_percent,omitempty" db:"discount_percent"`
+
+	// Variants lists the versions of the product found on the page at the last
+	// check. Setting VariantSKU or VariantAttributes pins the tracked one, so
+	// CurrentPrice follows that variant rather than the page's headline price.
+	Variants          []Variant         `json:"variants,omitempty" db:"variants"`
+	VariantSKU        string            `json:"variant_sku,omitempty" db:"variant_sku"`
+	VariantAttributes map[string]string `json:"variant_attributes,omitempty" db:"variant_attributes"`
+
+	// Offers are the sellers found on the page at the last check. They are
+	// stored in their own table rather than on the product row.
+	Offers []Offer `json:"offers,omitempty" db:"-"`
+
+	// Schedule is how often the product is checked: an interval ("10m") or
+	// a cron expression ("0 9 * * *"). Empty falls back to the store's
+	// SiteSchedule, then to the configured check interval.
+	Schedule    string    `json:"schedule,omitempty" db:"schedule"`
+	NextCheckAt time.Time `json:"next_check_at,omitempty" db:"next_check_at"` // zero until the first check
+}
+
+// SiteSchedule is the default check schedule of the products of one store
+type SiteSchedule struct {
+	Host      string    `json:"host" db:"host"`
+	Schedule  string    `json:"schedule" db:"schedule"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Variant is one purchasable version of a product: a size, color, capacity...
+type Variant struct {
+	SKU          string            `json:"sku,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"` // e.g. {"color": "preto", "storage": "256GB"}
+	Price        float64           `json:"price,omitempty"`
+	Currency     string            `json:"currency,omitempty"`
+	Availability Availability      `json:"availability,omitempty"` // "" when the page doesn't say
+	URL          string            `json:"url,omitempty"`
+}
+
+// Availability is the stock state of a product
+type Availability string
+
+// Availability states
+const (
+	AvailabilityUnknown      Availability = "unknown"
+	AvailabilityInStock      Availability = "in_stock"
+	AvailabilityOutOfStock   Availability = "out_of_stock"
+	AvailabilityPreorder     Availability = "preorder"
+	AvailabilityDiscontinued Availability = "discontinued"
+)
+
+// Purchasable reports whether the product can be ordered in state a
+func (a Availability) Purchasable() bool {
+	return a == AvailabilityInStock || a == AvailabilityPreorder
+}
+
+// BackInStock reports whether going from one state to another means a product
+// that couldn't be bought is in stock again
+func BackInStock(from, to Availability) bool {
+	return to == AvailabilityInStock && (from == AvailabilityOutOfStock || from == AvailabilityDiscontinued)
+}
+
+// SetAvailability sets the availability state, keeping IsAvailable in step.
+// An empty state is stored as unknown.
+func (p *Product) SetAvailability(a Availability) {
+	if a == "" {
+		a = AvailabilityUnknown
+	}
+	p.Availability = a
+	p.IsAvailable = a.Purchasable()
+}
+
+// TotalPrice is the landed price: item price plus shipping
+func (p *Product) TotalPrice() float64 {
+	return p.CurrentPrice + p.ShippingCost
+}
+
+// PinsVariant reports whether the product tracks a specific variant
+func (p *Product) PinsVariant() bool {
+	return p.VariantSKU != "" || len(p.VariantAttributes) > 0
+}
+
+// Matches reports whether v is the variant pinned by sku or, when sku is
+// empty, by attrs. Attribute names and values are compared ignoring case and
+// spaces, so "256 GB" matches "256gb".
+func (v *Variant) Matches(sku string, attrs map[string]string) bool {
+	if sku != "" {
+		return strings.EqualFold(strings.TrimSpace(v.SKU), strings.TrimSpace(sku))
+	}
+	if len(attrs) == 0 {
+		return false
+	}
+	have := make(map[string]string, len(v.Attributes))
+	for k, val := range v.Attributes {
+		have[normalizeAttr(k)] = normalizeAttr(val)
+	}
+	for k, want := range attrs {
+		if have[normalizeAttr(k)] != normalizeAttr(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeAttr lowercases s and drops its whitespace
+func normalizeAttr(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
+// Outcomes of the last check of a product
+const (
+	ScrapeStatusOK         = "ok"
+	ScrapeStatusFailed     = "failed"     // transient or unexpected failure
+	ScrapeStatusGone       = "gone"       // the store no longer has the page
+	ScrapeStatusDisallowed = "disallowed" // robots.txt forbids fetching the page
+	ScrapeStatusBlocked    = "blocked"    // the store served an anti-bot page instead
+)
+
+// ScrapeRun records the outcome of one check of a product
+type ScrapeRun struct {
+	ID           uuid.UUID    `json:"id" db:"id"`
+	ProductID    uuid.UUID    `json:"product_id" db:"product_id"`
+	Status       string       `json:"status" db:"status"` // one of the ScrapeStatus* values
+	Error        string       `json:"error,omitempty" db:"error"`
+	Price        float64      `json:"price,omitempty" db:"price"` // price after the check; 0 when it failed
+	Availability Availability `json:"availability,omitempty" db:"availability"`
+	StartedAt    time.Time    `json:"started_at" db:"started_at"`
+	DurationMs   int64        `json:"duration_ms" db:"duration_ms"`
+}
+
+// ScrapeJob is a product's entry in the scrape queue. A worker leases due
+// jobs so that no other worker checks the same product until the lease is
+// released or expires.
+type ScrapeJob struct {
+	ProductID      uuid.UUID `json:"product_id" db:"product_id"`
+	NextRunAt      time.Time `json:"next_run_at" db:"next_run_at"`
+	Attempts       int       `json:"attempts" db:"attempts"` // failed checks in a row, plus the leased one
+	LeaseOwner     string    `json:"lease_owner,omitempty" db:"lease_owner"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at,omitempty" db:"lease_expires_at"`
+	LastError      string    `json:"last_error,omitempty" db:"last_error"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // PriceHistory represents the price history of a product
 type PriceHistory struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	ProductID uuid.UUID `json:"product_id" db:"product_id"`
-	Price     float64   `json:"price" db:"price"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID              uuid.UUID    `json:"id" db:"id"`
+	ProductID       uuid.UUID    `json:"product_id" db:"product_id"`
+	Price           float64      `json:"price" db:"price"`
+	Shipping        float64      `json:"shipping" db:"shipping"`
+	FreeShipping    bool         `json:"free_shipping" db:"free_shipping"`
+	ListPrice       float64      `json:"list_price,omitempty" db:"list_price"`
+	DiscountPercent float64      `json:"discount_percent,omitempty" db:"discount_percent"` // advertised, see Product
+	Availability    Availability `json:"availability,omitempty" db:"availability"`
+	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
 }
 
-// Alert represents a price alert set by the user
-type Alert struct {
+// TotalPrice is the landed price: item price plus shipping
+func (h *PriceHistory) TotalPrice() float64 {
+	return h.Price + h.Shipping
+}
+
+// Offer is one seller's offer for a product on a marketplace page. Offers are
+// recorded alongside the price history whenever the seller list changes.
+type Offer struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	ProductID    uuid.UUID `json:"product_id" db:"product_id"`
-	TargetPrice  float64   `json:"target_price" db:"target_price"`
-	IsActive     bool      `json:"is_active" db:"is_active"`
+	Seller       string    `json:"seller" db:"seller"`
+	Price        float64   `json:"price" db:"price"`
+	Shipping     float64   `json:"shipping" db:"shipping"` // 0 when free or not shown
+	FreeShipping bool      `json:"free_shipping" db:"free_shipping"`
+	Currency     string    `json:"currency" db:"currency"`
+	Condition    string    `json:"condition,omitempty" db:"condition"`     // one of the OfferCondition* values, "" when not shown
+	Fulfillment  string    `json:"fulfillment,omitempty" db:"fulfillment"` // who ships it, e.g. "Full" or "Amazon"
+	BuyBox       bool      `json:"buy_box" db:"buy_box"`                   // the offer the page sells by default
+	IsAvailable  bool      `json:"is_available" db:"is_available"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	NotifiedAt   time.Time `json:"notified_at,omitempty" db:"notified_at"`
-	NotificationType string `json:"notification_type" db:"notification_type"` // email, telegram, etc.
 }
+
+// Offer conditions
+const (
+	OfferConditionNew         = "new"
+	OfferConditionUsed        = "used"
+	OfferConditionRefurbished = "refurbished"
+)
+
+// Alert represents a price alert set by the user
+type Alert struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	ProductID        uuid.UUID `json:"product_id" db:"product_id"`
+	TargetPrice      float64   `json:"target_price" db:"target_price"`
+	IsActive         bool      `json:"is_active" db:"is_active"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	NotifiedAt       time.Time `json:"notified_at,omitempty" db:"notified_at"`
+	NotificationType string    `json:"notification_type" db:"notification_type"`   // email, telegram, etc.
+	UseTotalPrice    bool      `json:"use_total_price" db:"use_total_price"`       // compare TargetPrice with price plus shipping
+	SkipFakeDiscount bool      `json:"skip_fake_discount" db:"skip_fake_discount"` // don't fire on a discount the price history doesn't back
+	Kind             string    `json:"kind" db:"kind"`                             // one of the AlertKind* values; empty means AlertKindPrice
+}
+
+// Alert kinds
+const (
+	AlertKindPrice       = "price"         // the price drops to TargetPrice or below
+	AlertKindBackInStock = "back_in_stock" // the product is in stock again after being out of stock or discontinued
+)
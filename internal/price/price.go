@@ -0,0 +1,259 @@
+// Package price parses price labels scraped from store pages, such as
+// "R$ 1.299,90", "1,299.90 USD" or "€ 12,-", into amounts.
+package price
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNoAmount is returned when the text contains no number
+	ErrNoAmount = errors.New("no amount found")
+	// ErrInstallment is returned when the text only describes an installment
+	// ("12x de R$ 108,33") rather than the full price
+	ErrInstallment = errors.New("text describes an installment, not a price")
+	// ErrMalformed is returned when the number's separators make no sense
+	ErrMalformed = errors.New("malformed number")
+	// ErrZero is returned for amounts that are zero
+	ErrZero = errors.New("amount is zero")
+)
+
+// ParseError describes a price label that could not be parsed
+type ParseError struct {
+	Text string
+	Err  error
+}
+
+// Error implements error
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("price: cannot parse %q: %v", e.Text, e.Err)
+}
+
+// Unwrap returns the underlying sentinel error
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Amount is a parsed price. Currency is an ISO 4217 code, empty when the text
+// had no currency and none was assumed.
+type Amount struct {
+	Value    float64
+	Currency string
+}
+
+// Locale describes how numbers are written
+type Locale struct {
+	Decimal   rune
+	Thousands rune
+}
+
+var (
+	// Comma is used in Brazil and most of continental Europe: 1.299,90
+	Comma = Locale{Decimal: ',', Thousands: '.'}
+	// Dot is used in the US, the UK and by schema.org: 1,299.90
+	Dot = Locale{Decimal: '.', Thousands: ','}
+)
+
+// LocaleFor returns the number format for a BCP 47 language tag such as "pt-BR"
+func LocaleFor(tag string) (Locale, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return Locale{}, false
+	}
+	lang := strings.SplitN(strings.ReplaceAll(tag, "_", "-"), "-", 2)[0]
+	switch lang {
+	case "pt", "es", "de", "fr", "it", "nl", "pl", "ru", "tr", "da", "sv", "nb", "fi":
+		if tag == "es-mx" || tag == "es-us" {
+			return Dot, true
+		}
+		return Comma, true
+	case "en", "ja", "zh", "ko", "he", "th":
+		return Dot, true
+	}
+	return Locale{}, false
+}
+
+// Options tune how ambiguous labels are read
+type Options struct {
+	// Locale decides what a lone separator followed by three digits means
+	// ("1.299"). The zero value infers it from the currency.
+	Locale Locale
+	// Currency is assumed when the text names none
+	Currency string
+}
+
+// Parse reads the first amount in text, inferring the number format
+func Parse(text string) (Amount, error) {
+	return ParseWith(text, Options{})
+}
+
+// ParseWith reads the first amount in text using opts
+func ParseWith(text string, opts Options) (Amount, error) {
+	normalized := normalize(text)
+	if normalized == "" {
+		return Amount{}, &ParseError{Text: text, Err: ErrNoAmount}
+	}
+
+	// Drop installment plans ("ou 12x de R$ 108,33"); if nothing is left, the
+	// label was only an installment.
+	if loc := installmentRe.FindStringIndex(normalized); loc != nil {
+		normalized = strings.TrimSpace(normalized[:loc[0]])
+		if !digitRe.MatchString(normalized) {
+			return Amount{}, &ParseError{Text: text, Err: ErrInstallment}
+		}
+	}
+
+	currency := detectCurrency(normalized)
+	if currency == "" {
+		currency = strings.ToUpper(opts.Currency)
+	}
+
+	number := numberRe.FindString(normalized)
+	if number == "" {
+		return Amount{}, &ParseError{Text: text, Err: ErrNoAmount}
+	}
+
+	locale := opts.Locale
+	if locale == (Locale{}) {
+		locale = currencyLocale[currency]
+	}
+	value, err := parseNumber(number, locale)
+	if err != nil {
+		return Amount{}, &ParseError{Text: text, Err: err}
+	}
+	if value <= 0 {
+		return Amount{}, &ParseError{Text: text, Err: ErrZero}
+	}
+	return Amount{Value: value, Currency: currency}, nil
+}
+
+var (
+	digitRe = regexp.MustCompile(`\d`)
+	// numberRe matches digits with separators between them (spaces only before
+	// a group of three), plus the ",-" / ".--" notation for whole amounts
+	numberRe = regexp.MustCompile(`\d+(?:[.,']\d+| \d{3}\b)*(?:[.,]-{1,2})?`)
+	// installmentRe finds where an installment plan starts in a label
+	installmentRe = regexp.MustCompile(`(?i)(\b(?:em\s+até\s+|ou\s+|até\s+)?\d{1,2}\s*x\s*(?:de\s+|sem\s+juros|\d|R\$|\$)|\bparcelas?\b|\bsem juros\b|/\s*m[eê]s\b|\bper month\b|/\s*mo\b|\b\d{1,2}\s+(?:installments|vezes|cuotas)\b)`)
+)
+
+// normalize trims the text and turns exotic spaces into plain ones
+func normalize(text string) string {
+	text = strings.NewReplacer(" ", " ", " ", " ", " ", " ", "\t", " ", "\n", " ").Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// currencySymbols maps symbols to ISO codes; longer symbols must come first
+var currencySymbols = []struct{ symbol, code string }{
+	{"R$", "BRL"},
+	{"US$", "USD"},
+	{"U$", "USD"},
+	{"C$", "CAD"},
+	{"A$", "AUD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"$", "USD"},
+}
+
+var isoCodeRe = regexp.MustCompile(`\b(BRL|USD|EUR|GBP|JPY|CAD|AUD|ARS|MXN|CLP|COP|PEN|UYU|CHF)\b`)
+
+// currencyLocale is the usual number format for each currency
+var currencyLocale = map[string]Locale{
+	"BRL": Comma, "EUR": Comma, "ARS": Comma, "CLP": Comma, "COP": Comma, "UYU": Comma,
+	"USD": Dot, "GBP": Dot, "JPY": Dot, "CAD": Dot, "AUD": Dot, "MXN": Dot, "PEN": Dot, "CHF": Dot,
+}
+
+// detectCurrency finds an ISO code or currency symbol in text
+func detectCurrency(text string) string {
+	if m := isoCodeRe.FindString(strings.ToUpper(text)); m != "" {
+		return m
+	}
+	for _, cs := range currencySymbols {
+		if strings.Contains(text, cs.symbol) {
+			return cs.code
+		}
+	}
+	return ""
+}
+
+// parseNumber interprets the separators in a number such as "1.299,90".
+// locale is only consulted when a single separator is followed by exactly three
+// digits; without it such separators are read as thousands.
+func parseNumber(s string, locale Locale) (float64, error) {
+	// "12,-" and "12.--" mean a whole amount
+	if i := strings.IndexAny(s, "-"); i > 0 {
+		s = s[:i-1]
+	}
+	s = strings.NewReplacer(" ", "", "'", "").Replace(s)
+
+	lastDot, lastComma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	var decimal byte
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// Both present: whichever comes last is the decimal separator
+		decimal = '.'
+		if lastComma > lastDot {
+			decimal = ','
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		sep := byte('.')
+		last := lastDot
+		if lastComma >= 0 {
+			sep, last = ',', lastComma
+		}
+		digitsAfter := len(s) - last - 1
+		switch {
+		case strings.Count(s, string(sep)) > 1:
+			// "1.299.000" - repeated separators group thousands
+		case digitsAfter == 3:
+			if locale.Decimal == rune(sep) {
+				decimal = sep
+			}
+		default:
+			decimal = sep
+		}
+	}
+
+	intPart, fracPart := s, ""
+	if decimal != 0 {
+		i := strings.LastIndexByte(s, decimal)
+		intPart, fracPart = s[:i], s[i+1:]
+		if fracPart == "" || strings.ContainsAny(fracPart, ".,") {
+			return 0, ErrMalformed
+		}
+	}
+
+	// Whatever separators remain must group digits in threes
+	groups := strings.FieldsFunc(intPart, func(r rune) bool { return r == '.' || r == ',' })
+	if len(groups) == 0 {
+		return 0, ErrMalformed
+	}
+	if len(groups) > 1 {
+		if len(groups[0]) > 3 {
+			return 0, ErrMalformed
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return 0, ErrMalformed
+			}
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.Join(groups, "")+"."+fracPart+"0", 64)
+	if err != nil {
+		return 0, ErrMalformed
+	}
+	return value, nil
+}
+
+// Discount is how far now sits below was, in percent rounded to 0.1%. It is
+// negative when now is higher, and 0 when was is not positive.
+func Discount(was, now float64) float64 {
+	if was <= 0 {
+		return 0
+	}
+	return math.Round((1-now/was)*1000) / 10
+}
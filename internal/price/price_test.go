@@ -0,0 +1,137 @@
+package price
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		value    float64
+		currency string
+	}{
+		{"R$ 1.299,90", 1299.90, "BRL"},
+		{"R$1.299", 1299, "BRL"},
+		{"R$ 49,90", 49.90, "BRL"},
+		{"1,299.90 USD", 1299.90, "USD"},
+		{"$1,299", 1299, "USD"},
+		{"US$ 12.50", 12.50, "USD"},
+		{"€ 12,-", 12, "EUR"},
+		{"£ 1 299.00", 1299, "GBP"},
+		{"CHF 1'299.50", 1299.50, "CHF"},
+		{"R$ 1.299.000", 1299000, "BRL"},
+		{"R$ 1.299,90 ou 12x de R$ 108,33", 1299.90, "BRL"},
+		{"Por: R$ 999,00 à vista", 999, "BRL"},
+		{"12.5", 12.5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.text, err)
+			}
+			if got.Value != tt.value || got.Currency != tt.currency {
+				t.Errorf("Parse(%q) = %v %q, want %v %q", tt.text, got.Value, got.Currency, tt.value, tt.currency)
+			}
+		})
+	}
+}
+
+func TestParseWith(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		opts  Options
+		value float64
+		cur   string
+	}{
+		{"comma locale reads a lone dot as thousands", "1.299", Options{Locale: Comma}, 1299, ""},
+		{"dot locale reads a lone dot as decimal", "1.299", Options{Locale: Dot}, 1.299, ""},
+		{"dot locale reads a lone comma as thousands", "1,299", Options{Locale: Dot}, 1299, ""},
+		{"assumed currency", "49,90", Options{Currency: "brl"}, 49.90, "BRL"},
+		{"named currency wins over the assumed one", "US$ 10", Options{Currency: "BRL"}, 10, "USD"},
+		{"currency picks the locale", "1.299", Options{Currency: "EUR"}, 1299, "EUR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWith(tt.text, tt.opts)
+			if err != nil {
+				t.Fatalf("ParseWith(%q) error: %v", tt.text, err)
+			}
+			if got.Value != tt.value || got.Currency != tt.cur {
+				t.Errorf("ParseWith(%q) = %v %q, want %v %q", tt.text, got.Value, got.Currency, tt.value, tt.cur)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  error
+	}{
+		{"", ErrNoAmount},
+		{"   ", ErrNoAmount},
+		{"Indisponível", ErrNoAmount},
+		{"12x de R$ 108,33", ErrInstallment},
+		{"ou 10x sem juros", ErrInstallment},
+		{"R$ 0,00", ErrZero},
+		{"1.29.90", ErrMalformed},
+		{"1,2345.67", ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := Parse(tt.text)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.text, err, tt.err)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) || pe.Text != tt.text {
+				t.Errorf("Parse(%q) error %v is not a *ParseError for the text", tt.text, err)
+			}
+		})
+	}
+}
+
+func TestLocaleFor(t *testing.T) {
+	tests := []struct {
+		tag    string
+		locale Locale
+		ok     bool
+	}{
+		{"pt-BR", Comma, true},
+		{"pt_br", Comma, true},
+		{"de", Comma, true},
+		{"es-MX", Dot, true},
+		{"en-US", Dot, true},
+		{"ja", Dot, true},
+		{"", Locale{}, false},
+		{"xx", Locale{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := LocaleFor(tt.tag)
+		if got != tt.locale || ok != tt.ok {
+			t.Errorf("LocaleFor(%q) = %v, %v, want %v, %v", tt.tag, got, ok, tt.locale, tt.ok)
+		}
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	tests := []struct {
+		was, now float64
+		want     float64
+	}{
+		{100, 75, 25},
+		{1299, 999, 23.1},
+		{3, 2, 33.3},
+		{100, 100, 0},
+		{100, 120, -20},
+		{0, 50, 0},
+	}
+	for _, tt := range tests {
+		if got := Discount(tt.was, tt.now); got != tt.want {
+			t.Errorf("Discount(%v, %v) = %v, want %v", tt.was, tt.now, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package schedule parses the check schedules of products and stores: either
+// a fixed interval ("10m", "24h") or a cron expression ("0 */6 * * *",
+// "@daily", "CRON_TZ=America/Sao_Paulo 0 9 * * *").
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// MinInterval is the shortest interval a schedule may ask for
+const MinInterval = time.Minute
+
+// Schedule tells when the next check is due
+type Schedule struct {
+	spec  string
+	every time.Duration // set for intervals
+	cron  cron.Schedule // set for cron expressions
+}
+
+// Parse reads an interval or a five-field cron expression
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d < MinInterval {
+			return nil, fmt.Errorf("interval %s is shorter than %s", d, MinInterval)
+		}
+		return &Schedule{spec: spec, every: d}, nil
+	}
+	c, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: not an interval or a cron expression: %w", spec, err)
+	}
+	// Five-field expressions can't fire more than once a minute, but
+	// "@every" takes any duration
+	first := c.Next(time.Now())
+	if gap := c.Next(first).Sub(first); gap < MinInterval {
+		return nil, fmt.Errorf("schedule %q runs every %s, more often than %s", spec, gap, MinInterval)
+	}
+	return &Schedule{spec: spec, cron: c}, nil
+}
+
+// Every returns a schedule that runs every d
+func Every(d time.Duration) *Schedule {
+	return &Schedule{spec: d.String(), every: d}
+}
+
+// Next returns the first time the schedule is due after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(t)
+	}
+	return t.Add(s.every)
+}
+
+// String returns the spec the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.spec
+}
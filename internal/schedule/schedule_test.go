@@ -0,0 +1,50 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	start := time.Date(2024, 3, 10, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"10m", start.Add(10 * time.Minute)},
+		{" 24h ", start.Add(24 * time.Hour)},
+		{"1m", start.Add(time.Minute)},
+		{"0 */6 * * *", time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)},
+		{"* * * * *", start.Add(time.Minute)},
+		{"@daily", time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"@every 1m", start.Add(time.Minute)},
+		{"CRON_TZ=America/Sao_Paulo 0 9 * * *", time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.spec, err)
+			}
+			if got := s.Next(start); !got.Equal(tt.next) {
+				t.Errorf("Next(%v) = %v, want %v", start, got, tt.next)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"   ",
+		"30s",
+		"@every 1s",
+		"@every 59s",
+		"every day",
+		"0 0 * *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -6,7 +6,7 @@ import (
 
 	"github.com/go-co-op/gocron/v2"
 	"github.com/rs/zerolog/log"
-	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/alerts"
 	"github.com/PedroM2626/PriceWatcher/internal/scraper"
 	"github.com/PedroM2626/PriceWatcher/internal/storage"
 )
@@ -15,7 +15,10 @@ import (
 type Scheduler struct {
 	scheduler gocron.Scheduler
 	scraper   *scraper.PriceScraper
-	storage   storage.Storage
+	alerts    *alerts.Evaluator
+
+	ctx    context.Context // cancelled by Stop to interrupt in-flight checks
+	cancel context.CancelFunc
 }
 
 // NewScheduler creates a new scheduler instance
@@ -25,19 +28,25 @@ func NewScheduler(scraper *scraper.PriceScraper, storage storage.Storage) (*Sche
 		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		scheduler: s,
 		scraper:   scraper,
-		storage:   storage,
+		alerts:    alerts.NewEvaluator(storage),
+		ctx:       ctx,
+		cancel:    cancel,
 	}, nil
 }
 
 // Start starts the scheduler
 func (s *Scheduler) Start() error {
-	// Schedule price checks to run every hour
+	// Look for due products every minute; each product's next check is set
+	// from its own schedule, its store's or the default interval. A run that
+	// is still draining the queue makes the next one wait.
 	_, err := s.scheduler.NewJob(
-		gocron.DurationJob(1*time.Hour),
-		gocron.NewTask(s.CheckAllProducts),
+		gocron.DurationJob(time.Minute),
+		gocron.NewTask(s.CheckDueProducts),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
 	)
 	if err != nil {
 		return err
@@ -48,13 +57,14 @@ func (s *Scheduler) Start() error {
 	log.Info().Msg("Scheduler started")
 
 	// Run initial check
-	go s.CheckAllProducts()
+	go s.CheckDueProducts()
 
 	return nil
 }
 
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
+	s.cancel()
 	if s.scheduler != nil {
 		err := s.scheduler.Shutdown()
 		if err != nil {
@@ -63,114 +73,12 @@ func (s *Scheduler) Stop() {
 	}
 }
 
-// CheckAllProducts checks all products for price updates
-func (s *Scheduler) CheckAllProducts() {
-	log.Info().Msg("Starting scheduled price check")
-
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
-	// Get all products from the database
-	products, err := s.storage.ListProducts(ctx, 0, 0) // 0, 0 means get all products
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to list products for price check")
-		return
-	}
-
-	log.Info().Int("count", len(products)).Msg("Checking prices for products")
-
-	// Check each product
-	for _, product := range products {
-		// Skip if the URL is empty
-		if product.URL == "" {
-			continue
-		}
-
-		// Scrape the product
-		updatedProduct, err := s.scraper.Scrape(ctx, product.URL)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Str("product_id", product.ID.String()).
-				Str("url", product.URL).
-				Msg("Failed to scrape product")
-			continue
-		}
-
-		// Update the product in the database
-		err = s.storage.UpdateProduct(ctx, updatedProduct)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Str("product_id", updatedProduct.ID.String()).
-				Msg("Failed to update product")
-			continue
-		}
-
-		// Check if price changed
-		if product.CurrentPrice != updatedProduct.CurrentPrice {
-			log.Info().
-				Str("product_id", updatedProduct.ID.String()).
-				Float64("old_price", product.CurrentPrice).
-				Float64("new_price", updatedProduct.CurrentPrice).
-				Msg("Price updated")
-
-			// Trigger price alerts
-			s.checkPriceAlerts(ctx, product, updatedProduct)
-		}
-	}
-}
-
-// checkPriceAlerts checks if any price alerts should be triggered
-func (s *Scheduler) checkPriceAlerts(ctx context.Context, oldProduct, newProduct *models.Product) {
-	// Get all active alerts for this product
-	alerts, err := s.storage.GetActiveAlertsForProduct(ctx, newProduct.ID)
-	if err != nil {
-		log.Error().
-			Err(err).
-			Str("product_id", newProduct.ID.String()).
-			Msg("Failed to get alerts for product")
-		return
+// CheckDueProducts works through the scrape queue, checking every product
+// that is due with the scraper's workers and triggering the alerts their
+// changes call for. Each check has its own timeout, so large catalogs are
+// spread over as many runs as they need instead of being cut off.
+func (s *Scheduler) CheckDueProducts() {
+	if n := s.scraper.ProcessDue(s.ctx, s.alerts.HandleCheck); n > 0 {
+		log.Info().Int("count", n).Msg("Checked due products")
 	}
-
-	for _, alert := range alerts {
-		// Check if the price is below the target price
-		if newProduct.CurrentPrice <= alert.TargetPrice {
-			// Trigger the alert
-			err := s.triggerAlert(ctx, alert, newProduct, oldProduct.CurrentPrice)
-			if err != nil {
-				log.Error().
-					Err(err).
-					Str("alert_id", alert.ID.String()).
-					Msg("Failed to trigger alert")
-			}
-
-			// Mark the alert as notified
-			alert.NotifiedAt = time.Now()
-			err = s.storage.UpdateAlert(ctx, alert)
-			if err != nil {
-				log.Error().
-					Err(err).
-					Str("alert_id", alert.ID.String()).
-					Msg("Failed to update alert")
-			}
-		}
-	}
-}
-
-// triggerAlert triggers a price alert
-func (s *Scheduler) triggerAlert(ctx context.Context, alert *models.Alert, product *models.Product, oldPrice float64) error {
-	// TODO: Implement alert triggering logic
-	// This would use the notifier package to send notifications
-	// based on the alert's notification type (email, telegram, etc.)
-
-	log.Info().
-		Str("alert_id", alert.ID.String()).
-		Str("product_id", product.ID.String()).
-		Float64("target_price", alert.TargetPrice).
-		Float64("current_price", product.CurrentPrice).
-		Msg("Price alert triggered")
-
-	return nil
 }
@@ -0,0 +1,146 @@
+package scraper
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+// AdaptivePolicy lets each product's check interval follow how it behaves
+// instead of using CheckInterval for all of them. Products with a schedule
+// of their own or of their store keep it.
+type AdaptivePolicy struct {
+	Enabled     bool
+	MinInterval time.Duration // never check a product more often than this
+	MaxInterval time.Duration // never wait longer than this between checks
+	Window      time.Duration // how far back the price history is read to measure volatility
+}
+
+// Default adaptive bounds, used when the policy is enabled without them
+const (
+	defaultAdaptiveMin    = 10 * time.Minute
+	defaultAdaptiveMax    = 24 * time.Hour
+	defaultAdaptiveWindow = 7 * 24 * time.Hour
+)
+
+const (
+	// checksPerChange is how many checks the interval aims for between two
+	// price changes of a volatile product
+	checksPerChange = 3
+	// stableFactor stretches the interval of products whose price didn't
+	// move during the whole window
+	stableFactor = 4
+	// maxFailureDoublings caps the backoff of products that keep failing
+	maxFailureDoublings = 5
+)
+
+// adaptiveInterval returns how long product waits for its next check. The
+// interval starts from the product's volatility (how often its price or
+// availability changed in the window), shrinks when the price nears an
+// alert's target and doubles for each failed check in a row, then is kept
+// within the policy's bounds.
+func (s *PriceScraper) adaptiveInterval(ctx context.Context, product *models.Product) time.Duration {
+	policy := s.config.Adaptive
+	interval := s.config.CheckInterval
+
+	days := int(math.Ceil(policy.Window.Hours() / 24))
+	history, err := s.storage.GetPriceHistory(ctx, product.ID, days)
+	if err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to load price history for scheduling")
+	} else {
+		interval = volatilityInterval(history, interval)
+	}
+
+	alerts, err := s.storage.GetActiveAlertsForProduct(ctx, product.ID)
+	if err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to load alerts for scheduling")
+	} else {
+		interval = time.Duration(float64(interval) * proximityFactor(product, alerts))
+	}
+
+	runs, err := s.storage.GetScrapeRuns(ctx, product.ID, maxFailureDoublings)
+	if err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to load scrape runs for scheduling")
+	} else {
+		interval <<= failureStreak(runs)
+	}
+
+	if interval < policy.MinInterval {
+		interval = policy.MinInterval
+	}
+	if interval > policy.MaxInterval {
+		interval = policy.MaxInterval
+	}
+	return interval
+}
+
+// volatilityInterval spreads checksPerChange checks over the average time
+// between changes in history (newest first). Histories too short to judge
+// keep base.
+func volatilityInterval(history []*models.PriceHistory, base time.Duration) time.Duration {
+	if len(history) < 4 {
+		return base
+	}
+	span := history[0].CreatedAt.Sub(history[len(history)-1].CreatedAt)
+	if span < 24*time.Hour {
+		return base
+	}
+
+	changes := 0
+	for i := 1; i < len(history); i++ {
+		newer, older := history[i-1], history[i]
+		if newer.Price != older.Price || newer.Availability != older.Availability {
+			changes++
+		}
+	}
+	if changes == 0 {
+		return base * stableFactor
+	}
+	return span / time.Duration(changes*checksPerChange)
+}
+
+// proximityFactor scales the interval down when an active alert is about to
+// fire: the price within 5% of its target quarters it, within 15% halves
+// it, and so does an unavailable product someone waits on
+func proximityFactor(product *models.Product, alerts []*models.Alert) float64 {
+	factor := 1.0
+	for _, alert := range alerts {
+		f := 1.0
+		if alert.Kind == models.AlertKindBackInStock {
+			if !product.Availability.Purchasable() {
+				f = 0.5
+			}
+		} else {
+			price := product.CurrentPrice
+			if alert.UseTotalPrice {
+				price = product.TotalPrice()
+			}
+			if price <= 0 || alert.TargetPrice <= 0 {
+				continue
+			}
+			switch gap := (price - alert.TargetPrice) / alert.TargetPrice; {
+			case gap <= 0.05:
+				f = 0.25
+			case gap <= 0.15:
+				f = 0.5
+			}
+		}
+		factor = math.Min(factor, f)
+	}
+	return factor
+}
+
+// failureStreak counts the failed checks at the head of runs (newest first)
+func failureStreak(runs []*models.ScrapeRun) int {
+	n := 0
+	for _, run := range runs {
+		if run.Status == models.ScrapeStatusOK {
+			break
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,165 @@
+package scraper
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+// priceChanges returns n history entries (newest first) spread evenly over
+// span, alternating between two prices when changing is set
+func priceChanges(now time.Time, n int, span time.Duration, changing bool) []*models.PriceHistory {
+	history := make([]*models.PriceHistory, n)
+	for i := range history {
+		price := 100.0
+		if changing && i%2 == 1 {
+			price = 90
+		}
+		history[i] = &models.PriceHistory{Price: price, CreatedAt: now.Add(-span * time.Duration(i) / time.Duration(n-1))}
+	}
+	return history
+}
+
+func TestVolatilityInterval(t *testing.T) {
+	now := time.Now()
+	base := time.Hour
+	tests := []struct {
+		name    string
+		history []*models.PriceHistory
+		want    time.Duration
+	}{
+		{name: "no history", want: base},
+		{name: "too few entries", history: priceChanges(now, 3, 72*time.Hour, true), want: base},
+		{name: "less than a day", history: priceChanges(now, 6, 12*time.Hour, true), want: base},
+		{name: "stable", history: priceChanges(now, 4, 72*time.Hour, false), want: base * stableFactor},
+		{name: "volatile", history: priceChanges(now, 7, 6*24*time.Hour, true), want: 8 * time.Hour},
+		{
+			name: "availability changes count",
+			history: []*models.PriceHistory{
+				{Price: 100, Availability: models.AvailabilityInStock, CreatedAt: now},
+				{Price: 100, Availability: models.AvailabilityOutOfStock, CreatedAt: now.Add(-24 * time.Hour)},
+				{Price: 100, Availability: models.AvailabilityOutOfStock, CreatedAt: now.Add(-48 * time.Hour)},
+				{Price: 100, Availability: models.AvailabilityOutOfStock, CreatedAt: now.Add(-72 * time.Hour)},
+			},
+			want: 24 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		if got := volatilityInterval(tt.history, base); got != tt.want {
+			t.Errorf("%s: volatilityInterval = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProximityFactor(t *testing.T) {
+	inStock := &models.Product{CurrentPrice: 104, ShippingCost: 20}
+	inStock.SetAvailability(models.AvailabilityInStock)
+	outOfStock := &models.Product{CurrentPrice: 104}
+	outOfStock.SetAvailability(models.AvailabilityOutOfStock)
+
+	tests := []struct {
+		name    string
+		product *models.Product
+		alerts  []*models.Alert
+		want    float64
+	}{
+		{name: "no alerts", product: inStock, want: 1},
+		{name: "far from target", product: inStock, alerts: []*models.Alert{{TargetPrice: 50}}, want: 1},
+		{name: "within 15%", product: inStock, alerts: []*models.Alert{{TargetPrice: 95}}, want: 0.5},
+		{name: "within 5%", product: inStock, alerts: []*models.Alert{{TargetPrice: 100}}, want: 0.25},
+		{name: "below target", product: inStock, alerts: []*models.Alert{{TargetPrice: 120}}, want: 0.25},
+		{name: "closest alert wins", product: inStock, alerts: []*models.Alert{{TargetPrice: 50}, {TargetPrice: 100}, {TargetPrice: 95}}, want: 0.25},
+		{name: "total price", product: inStock, alerts: []*models.Alert{{TargetPrice: 110, UseTotalPrice: true}}, want: 0.5},
+		{name: "back in stock waiting", product: outOfStock, alerts: []*models.Alert{{Kind: models.AlertKindBackInStock}}, want: 0.5},
+		{name: "back in stock already available", product: inStock, alerts: []*models.Alert{{Kind: models.AlertKindBackInStock}}, want: 1},
+		{name: "no price yet", product: &models.Product{}, alerts: []*models.Alert{{TargetPrice: 100}}, want: 1},
+	}
+	for _, tt := range tests {
+		if got := proximityFactor(tt.product, tt.alerts); got != tt.want {
+			t.Errorf("%s: proximityFactor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFailureStreak(t *testing.T) {
+	run := func(status string) *models.ScrapeRun { return &models.ScrapeRun{Status: status} }
+	tests := []struct {
+		name string
+		runs []*models.ScrapeRun
+		want int
+	}{
+		{name: "no runs"},
+		{name: "last check succeeded", runs: []*models.ScrapeRun{run(models.ScrapeStatusOK), run(models.ScrapeStatusFailed)}},
+		{name: "two failures", runs: []*models.ScrapeRun{run(models.ScrapeStatusFailed), run(models.ScrapeStatusBlocked), run(models.ScrapeStatusOK), run(models.ScrapeStatusFailed)}, want: 2},
+		{name: "all failed", runs: []*models.ScrapeRun{run(models.ScrapeStatusFailed), run(models.ScrapeStatusFailed), run(models.ScrapeStatusFailed)}, want: 3},
+	}
+	for _, tt := range tests {
+		if got := failureStreak(tt.runs); got != tt.want {
+			t.Errorf("%s: failureStreak = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	tests := []struct {
+		name     string
+		history  []*models.PriceHistory
+		target   float64 // active alert target price, 0 for none
+		failures int
+		want     time.Duration
+	}{
+		{name: "new product", want: time.Hour},
+		{name: "stable", history: priceChanges(now, 4, 72*time.Hour, false), want: 4 * time.Hour},
+		{name: "volatile", history: priceChanges(now, 7, 6*24*time.Hour, true), want: 8 * time.Hour},
+		{name: "failure streak", failures: 3, want: 8 * time.Hour},
+		{name: "capped at the maximum", history: priceChanges(now, 4, 72*time.Hour, false), failures: 5, want: 24 * time.Hour},
+		{name: "near an alert", target: 98, want: 15 * time.Minute},
+		{name: "raised to the minimum", history: priceChanges(now, 20, 24*time.Hour, true), target: 98, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewScraper(store, ScraperConfig{
+			CheckInterval: time.Hour,
+			Adaptive:      AdaptivePolicy{Enabled: true, MinInterval: 10 * time.Minute, MaxInterval: 24 * time.Hour},
+		})
+
+		product := &models.Product{Name: "Fone XYZ", URL: "https://loja.example/fone-xyz", CurrentPrice: 100, Currency: "BRL"}
+		product.SetAvailability(models.AvailabilityInStock)
+		if err := store.CreateProduct(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range tt.history {
+			h.ProductID = product.ID
+			if err := store.AddPriceHistory(ctx, h); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tt.target > 0 {
+			alert := &models.Alert{ProductID: product.ID, TargetPrice: tt.target, IsActive: true, NotificationType: "email"}
+			if err := store.CreateAlert(ctx, alert); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < tt.failures; i++ {
+			run := &models.ScrapeRun{ProductID: product.ID, Status: models.ScrapeStatusFailed, StartedAt: now.Add(-time.Duration(i) * time.Minute)}
+			if err := store.AddScrapeRun(ctx, run); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := s.adaptiveInterval(ctx, product); got != tt.want {
+			t.Errorf("%s: adaptiveInterval = %v, want %v", tt.name, got, tt.want)
+		}
+		store.Close()
+	}
+}
@@ -0,0 +1,258 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
+)
+
+// ErrBlocked is returned when the store served an anti-bot page (CAPTCHA,
+// "access denied", JavaScript challenge) instead of the product
+var ErrBlocked = errors.New("blocked by anti-bot protection")
+
+// blockBodyMarkers identify the challenge pages of common anti-bot vendors.
+// They are specific enough to be searched for anywhere in the page.
+// Cloudflare isn't listed: its challenge-platform script is also injected
+// into ordinary pages, so its challenges are told by the Cf-Mitigated header
+// or the title of a 403/503 page instead.
+var blockBodyMarkers = []string{
+	"_incapsula_resource", // Imperva
+	"incapsula incident id",
+	"px-captcha",              // PerimeterX
+	"captcha-delivery.com",    // DataDome
+	"distil_r_captcha",        // Distil
+	"/errors/validatecaptcha", // Amazon
+}
+
+// blockTitleMarkers are too generic for the whole page, but give a block page
+// away when they appear in its <title>
+var blockTitleMarkers = []string{
+	"captcha",
+	"access denied",
+	"acesso negado",
+	"attention required",
+	"just a moment",
+	"robot check",
+	"are you a robot",
+	"are you a human",
+	"pardon our interruption",
+	"security check",
+	"verificação de segurança",
+	"request blocked",
+}
+
+// cloudflareChallengeTitle is the <title> of Cloudflare's challenge pages
+const cloudflareChallengeTitle = "just a moment"
+
+// minPageSize is the size below which a page without a price is assumed to be
+// a block page rather than a product page with a layout we can't read
+const minPageSize = 1024
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// blockReason looks for the signs of a block page in a fetched response,
+// returning a description of what was found, or "" if the page looks genuine
+func (s *PriceScraper) blockReason(host string, res *FetchResult) string {
+	if res.Header.Get("Cf-Mitigated") == "challenge" {
+		return "cloudflare challenge"
+	}
+	if len(res.Body) == 0 {
+		return ""
+	}
+	body := bytes.ToLower(res.Body)
+	title := pageTitle(body)
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden, http.StatusServiceUnavailable:
+		if strings.Contains(title, cloudflareChallengeTitle) {
+			return "cloudflare challenge"
+		}
+		return ""
+	default:
+		return ""
+	}
+
+	for _, marker := range blockBodyMarkers {
+		if bytes.Contains(body, []byte(marker)) {
+			return fmt.Sprintf("page contains %q", marker)
+		}
+	}
+	if title != "" {
+		for _, marker := range blockTitleMarkers {
+			if strings.Contains(title, marker) {
+				return fmt.Sprintf("page title is %q", title)
+			}
+		}
+	}
+	if rule := s.config.Rules.Match(host); rule != nil {
+		for _, sig := range rule.BlockSignatures {
+			if bytes.Contains(body, []byte(strings.ToLower(sig))) {
+				return fmt.Sprintf("page contains %q (rule %q)", sig, rule.Name)
+			}
+		}
+	}
+	return ""
+}
+
+// pageTitle returns the whitespace-normalized <title> of a lowercased page
+func pageTitle(body []byte) string {
+	m := titleRe.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(string(m[1])), " ")
+}
+
+// missingExpected returns the first selector of the host's rule that finds
+// nothing on page, or "" when all expected elements are present
+func missingExpected(rule *siterules.Rule, page *Page) string {
+	if rule == nil {
+		return ""
+	}
+	for i := range rule.Expect {
+		sel := &rule.Expect[i]
+		if _, ok := sel.Find(page.Doc); !ok {
+			if sel.CSS != "" {
+				return sel.CSS
+			}
+			return sel.XPath
+		}
+	}
+	return ""
+}
+
+// HostBlockStats summarizes how often a store served block pages
+type HostBlockStats struct {
+	Host          string    `json:"host"`
+	Requests      int       `json:"requests"`   // pages fetched since startup
+	Blocked       int       `json:"blocked"`    // block pages since startup
+	BlockRate     float64   `json:"block_rate"` // share of the last blockWindow pages that were blocked
+	LastBlockedAt time.Time `json:"last_blocked_at,omitempty"`
+	LastReason    string    `json:"last_reason,omitempty"`
+}
+
+// blockWindow is the number of recent pages the block rate is computed over
+const blockWindow = 50
+
+// blockStats tracks block pages per host
+type blockStats struct {
+	mu    sync.Mutex
+	hosts map[string]*hostBlocks
+}
+
+// hostBlocks is the block history of one host
+type hostBlocks struct {
+	HostBlockStats
+	recent []bool // ring buffer of the last blockWindow outcomes
+	next   int
+}
+
+// newBlockStats creates empty statistics
+func newBlockStats() *blockStats {
+	return &blockStats{hosts: make(map[string]*hostBlocks)}
+}
+
+// record counts a fetched page of host; reason is non-empty for block pages
+func (b *blockStats) record(host, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.host(host)
+	h.Requests++
+	if len(h.recent) < blockWindow {
+		h.recent = append(h.recent, false)
+		h.next = len(h.recent) - 1
+	} else {
+		h.next = (h.next + 1) % blockWindow
+		h.recent[h.next] = false
+	}
+	if reason != "" {
+		b.markBlocked(h, reason)
+	}
+}
+
+// blocked flags the last recorded page of host as a block page, for blocks
+// that are only noticed after extraction
+func (b *blockStats) blocked(host, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.host(host)
+	if len(h.recent) == 0 || h.recent[h.next] {
+		return
+	}
+	b.markBlocked(h, reason)
+}
+
+// markBlocked updates h for a block page; b.mu must be held
+func (b *blockStats) markBlocked(h *hostBlocks, reason string) {
+	h.recent[h.next] = true
+	h.Blocked++
+	h.LastBlockedAt = time.Now()
+	h.LastReason = reason
+	h.BlockRate = h.rate()
+
+	log.Warn().
+		Str("host", h.Host).
+		Str("reason", reason).
+		Float64("block_rate", h.BlockRate).
+		Msg("Store served a block page")
+}
+
+// host returns the history of host, creating it on first use; b.mu must be held
+func (b *blockStats) host(host string) *hostBlocks {
+	h, ok := b.hosts[host]
+	if !ok {
+		h = &hostBlocks{HostBlockStats: HostBlockStats{Host: host}}
+		b.hosts[host] = h
+	}
+	return h
+}
+
+// rate is the share of blocked pages in the recent window
+func (h *hostBlocks) rate() float64 {
+	if len(h.recent) == 0 {
+		return 0
+	}
+	n := 0
+	for _, blocked := range h.recent {
+		if blocked {
+			n++
+		}
+	}
+	return float64(n) / float64(len(h.recent))
+}
+
+// snapshot returns the statistics of every host, worst first
+func (b *blockStats) snapshot() []HostBlockStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]HostBlockStats, 0, len(b.hosts))
+	for _, h := range b.hosts {
+		st := h.HostBlockStats
+		st.BlockRate = h.rate()
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].BlockRate != out[j].BlockRate {
+			return out[i].BlockRate > out[j].BlockRate
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+// BlockStats returns the per-host block page statistics since startup
+func (s *PriceScraper) BlockStats() []HostBlockStats {
+	return s.blocks.snapshot()
+}
@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
+)
+
+func TestBlockReason(t *testing.T) {
+	rules, err := siterules.NewSet(&siterules.Rule{
+		Name:            "loja",
+		Hosts:           []string{"loja.example"},
+		BlockSignatures: []string{"Tráfego incomum detectado"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewScraper(nil, ScraperConfig{Rules: rules})
+
+	challenge := `<html><head><title>Just a moment...</title></head><body><div id="challenge"></div></body></html>`
+	product := `<html><head><title>Fone XYZ | Loja</title></head><body><h1>Fone XYZ</h1><span class="price">R$ 199,90</span></body></html>`
+
+	tests := []struct {
+		name   string
+		host   string
+		status int
+		header http.Header
+		body   string
+		want   string // substring of the reason, "" for a genuine page
+	}{
+		{name: "product page", host: "shop.example", status: 200, body: product},
+		{name: "empty body", host: "shop.example", status: 200},
+		{
+			name:   "cloudflare header",
+			host:   "shop.example",
+			status: 403,
+			header: http.Header{"Cf-Mitigated": {"challenge"}},
+			body:   product,
+			want:   "cloudflare challenge",
+		},
+		{
+			name:   "cloudflare header without body",
+			host:   "shop.example",
+			status: 200,
+			header: http.Header{"Cf-Mitigated": {"challenge"}},
+			want:   "cloudflare challenge",
+		},
+		{name: "cloudflare title on 403", host: "shop.example", status: 403, body: challenge, want: "cloudflare challenge"},
+		{name: "cloudflare title on 503", host: "shop.example", status: 503, body: challenge, want: "cloudflare challenge"},
+		{name: "other 403 page", host: "shop.example", status: 403, body: `<html><head><title>Forbidden</title></head></html>`},
+		{name: "challenge title on 404", host: "shop.example", status: 404, body: challenge},
+		{
+			name:   "cloudflare script on a product page",
+			host:   "shop.example",
+			status: 200,
+			body:   `<html><head><title>Fone XYZ</title><script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script></head></html>`,
+		},
+		{
+			name:   "vendor marker",
+			host:   "shop.example",
+			status: 200,
+			body:   `<html><body><div id="px-captcha"></div></body></html>`,
+			want:   "px-captcha",
+		},
+		{
+			name:   "block title",
+			host:   "shop.example",
+			status: 200,
+			body:   "<html><head><title>\n  Acesso   Negado\n</title></head></html>",
+			want:   `"acesso negado"`,
+		},
+		{
+			name:   "rule signature",
+			host:   "loja.example",
+			status: 200,
+			body:   `<html><body><p>Tráfego incomum detectado na sua rede.</p></body></html>`,
+			want:   `rule "loja"`,
+		},
+		{
+			name:   "rule signature on another store",
+			host:   "shop.example",
+			status: 200,
+			body:   `<html><body><p>Tráfego incomum detectado na sua rede.</p></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		header := tt.header
+		if header == nil {
+			header = http.Header{}
+		}
+		res := &FetchResult{StatusCode: tt.status, Header: header, Body: []byte(tt.body)}
+		got := s.blockReason(tt.host, res)
+		if tt.want == "" {
+			if got != "" {
+				t.Errorf("%s: blockReason = %q, want none", tt.name, got)
+			}
+			continue
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: blockReason = %q, want it to mention %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBlockStats(t *testing.T) {
+	b := newBlockStats()
+	b.record("a.example", "")
+	b.record("a.example", "page contains \"px-captcha\"")
+	b.record("b.example", "")
+	b.blocked("b.example", "expected element missing")
+	b.blocked("b.example", "expected element missing") // same page, counted once
+	b.record("b.example", "")
+	b.record("b.example", "")
+	b.record("b.example", "")
+
+	stats := b.snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("snapshot returned %d hosts, want 2", len(stats))
+	}
+	tests := []struct {
+		host     string
+		requests int
+		blocked  int
+		rate     float64
+		reason   string
+	}{
+		{host: "a.example", requests: 2, blocked: 1, rate: 0.5, reason: "page contains \"px-captcha\""},
+		{host: "b.example", requests: 4, blocked: 1, rate: 0.25, reason: "expected element missing"},
+	}
+	for i, tt := range tests {
+		got := stats[i]
+		if got.Host != tt.host || got.Requests != tt.requests || got.Blocked != tt.blocked || got.BlockRate != tt.rate || got.LastReason != tt.reason {
+			t.Errorf("snapshot[%d] = %+v, want host %s, %d requests, %d blocked, rate %v, reason %q",
+				i, got, tt.host, tt.requests, tt.blocked, tt.rate, tt.reason)
+		}
+		if got.LastBlockedAt.IsZero() {
+			t.Errorf("snapshot[%d]: LastBlockedAt not set", i)
+		}
+	}
+}
+
+func TestBlockStatsWindow(t *testing.T) {
+	b := newBlockStats()
+	for i := 0; i < blockWindow; i++ {
+		b.record("shop.example", "captcha")
+	}
+	for i := 0; i < blockWindow/2; i++ {
+		b.record("shop.example", "")
+	}
+
+	got := b.snapshot()[0]
+	if got.Blocked != blockWindow || got.Requests != blockWindow+blockWindow/2 {
+		t.Errorf("totals = %d blocked of %d, want %d of %d", got.Blocked, got.Requests, blockWindow, blockWindow+blockWindow/2)
+	}
+	if got.BlockRate != 0.5 {
+		t.Errorf("BlockRate = %v, want 0.5 over the last %d pages", got.BlockRate, blockWindow)
+	}
+}
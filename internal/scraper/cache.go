@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PageCache keeps the last downloaded HTML of each product page on disk,
+// gzip-compressed and keyed by URL. It provides the body when a store answers
+// a conditional request with 304 Not Modified, and lets extraction be re-run
+// without fetching anything.
+type PageCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// CachedPage is a page read from the cache
+type CachedPage struct {
+	URL       string
+	Body      []byte
+	FetchedAt time.Time
+}
+
+// NewPageCache creates a cache in dir. Entries older than ttl are ignored and
+// removed; a zero ttl keeps them forever.
+func NewPageCache(dir string, ttl time.Duration) (*PageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create page cache directory: %w", err)
+	}
+	return &PageCache{dir: dir, ttl: ttl}, nil
+}
+
+// path returns the file holding url's page, spread over 256 subdirectories
+func (c *PageCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, key[:2], key+".html.gz")
+}
+
+// Get returns the cached page for url, if there is a fresh one
+func (c *PageCache) Get(url string) (*CachedPage, bool) {
+	filename := c.path(url)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+
+	// The URL is kept in the gzip header to rule out hash collisions
+	if zr.Name != url {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(zr.ModTime) > c.ttl {
+		os.Remove(filename)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, false
+	}
+	return &CachedPage{URL: url, Body: body, FetchedAt: zr.ModTime}, true
+}
+
+// Put stores body as the page of url
+func (c *PageCache) Put(url string, body []byte, fetchedAt time.Time) error {
+	filename := c.path(url)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial page
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".page-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	zw.Name = url
+	zw.ModTime = fetchedAt
+	_, err = zw.Write(body)
+	err = errors.Join(err, zw.Close(), tmp.Close())
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
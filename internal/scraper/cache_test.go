@@ -0,0 +1,172 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+func TestPageCache(t *testing.T) {
+	c, err := NewPageCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pageURL = "https://loja.example/p/1"
+
+	if _, ok := c.Get(pageURL); ok {
+		t.Fatal("Get found a page in an empty cache")
+	}
+
+	fetchedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	body := []byte("<html><body><h1>Produto</h1></body></html>")
+	if err := c.Put(pageURL, body, fetchedAt); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, ok := c.Get(pageURL)
+	if !ok {
+		t.Fatal("Get did not find the page just stored")
+	}
+	if string(got.Body) != string(body) || !got.FetchedAt.Equal(fetchedAt) || got.URL != pageURL {
+		t.Errorf("Get = %q fetched at %v, want %q fetched at %v", got.Body, got.FetchedAt, body, fetchedAt)
+	}
+	if _, ok := c.Get("https://loja.example/p/2"); ok {
+		t.Error("Get found a page for another URL")
+	}
+
+	// Pages older than the TTL are dropped
+	if err := c.Put(pageURL, body, time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(pageURL); ok {
+		t.Error("Get returned an expired page")
+	}
+	if _, err := os.Stat(c.path(pageURL)); !os.IsNotExist(err) {
+		t.Errorf("expired page was not removed: %v", err)
+	}
+}
+
+func TestConditionalHeader(t *testing.T) {
+	tests := []struct {
+		etag, lastModified string
+		want               http.Header
+	}{
+		{"", "", nil},
+		{`"v1"`, "", http.Header{"If-None-Match": {`"v1"`}}},
+		{"", "Sun, 10 Mar 2024 12:00:00 GMT", http.Header{"If-Modified-Since": {"Sun, 10 Mar 2024 12:00:00 GMT"}}},
+		{`W/"v2"`, "Sun, 10 Mar 2024 12:00:00 GMT", http.Header{"If-None-Match": {`W/"v2"`}, "If-Modified-Since": {"Sun, 10 Mar 2024 12:00:00 GMT"}}},
+	}
+	for _, tt := range tests {
+		got := conditionalHeader(&models.Product{ETag: tt.etag, LastModified: tt.lastModified})
+		if len(got) != len(tt.want) {
+			t.Errorf("conditionalHeader(%q, %q) = %v, want %v", tt.etag, tt.lastModified, got, tt.want)
+			continue
+		}
+		for k := range tt.want {
+			if got.Get(k) != tt.want.Get(k) {
+				t.Errorf("conditionalHeader(%q, %q) = %v, want %v", tt.etag, tt.lastModified, got, tt.want)
+			}
+		}
+	}
+}
+
+// conditionalStore serves one product page with an ETag, answering matching
+// If-None-Match requests with 304 Not Modified
+type conditionalStore struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	sent []string // If-None-Match of each page request
+}
+
+func newConditionalStore(t *testing.T) *conditionalStore {
+	t.Helper()
+	store := &conditionalStore{}
+	store.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		store.mu.Lock()
+		store.sent = append(store.sent, r.Header.Get("If-None-Match"))
+		store.mu.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`<html><body><h1>Produto</h1><span class="price">R$ 10,00</span></body></html>`))
+	}))
+	t.Cleanup(store.Close)
+	return store
+}
+
+// validators returns the If-None-Match headers received so far
+func (s *conditionalStore) validators() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+func TestScrapeProductNotModified(t *testing.T) {
+	srv := newConditionalStore(t)
+	s := NewScraper(nil, ScraperConfig{CacheDir: t.TempDir()})
+	product := &models.Product{URL: srv.URL + "/p/1"}
+
+	first, err := s.ScrapeProduct(context.Background(), product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ETag != `"v1"` || first.CurrentPrice != 10 {
+		t.Fatalf("first check = ETag %q price %v", first.ETag, first.CurrentPrice)
+	}
+
+	// The second check sends the validator, gets a 304 and extracts the
+	// cached page instead
+	product.ETag = first.ETag
+	second, err := s.ScrapeProduct(context.Background(), product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.CurrentPrice != 10 || second.Name != "Produto" || second.ETag != `"v1"` {
+		t.Errorf("second check = %q price %v ETag %q", second.Name, second.CurrentPrice, second.ETag)
+	}
+	if sent := srv.validators(); len(sent) != 2 || sent[0] != "" || sent[1] != `"v1"` {
+		t.Errorf("If-None-Match sent = %q, want none and then \"v1\"", sent)
+	}
+}
+
+func TestScrapeProductNotModifiedWithoutCache(t *testing.T) {
+	srv := newConditionalStore(t)
+	s := NewScraper(nil, ScraperConfig{})
+	product := &models.Product{URL: srv.URL + "/p/1", Name: "Produto", CurrentPrice: 12, ETag: `"v1"`}
+
+	got, err := s.ScrapeProduct(context.Background(), product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == product || got.CurrentPrice != 12 || got.ETag != `"v1"` {
+		t.Errorf("ScrapeProduct = %+v, want an unchanged copy of the product", got)
+	}
+}
+
+func TestScrapeProductSkipsValidatorsWithoutCachedPage(t *testing.T) {
+	srv := newConditionalStore(t)
+	// With a cache but no copy of the page, a 304 would leave nothing to
+	// extract from
+	s := NewScraper(nil, ScraperConfig{CacheDir: t.TempDir()})
+
+	got, err := s.ScrapeProduct(context.Background(), &models.Product{URL: srv.URL + "/p/1", ETag: `"v1"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := srv.validators(); got.CurrentPrice != 10 || len(sent) != 1 || sent[0] != "" {
+		t.Errorf("price %v after sending If-None-Match %q, want 10 after none", got.CurrentPrice, sent)
+	}
+}
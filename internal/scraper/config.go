@@ -0,0 +1,89 @@
+package scraper
+
+import (
+	"fmt"
+
+	"github.com/PedroM2626/PriceWatcher/internal/config"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
+)
+
+// ConfigFrom builds the scraper configuration from the scraper section of
+// the config file and the loaded site rules
+func ConfigFrom(cfg config.ScraperConfig, rules *siterules.Set) (ScraperConfig, error) {
+	proxies, err := NewProxyPool(ProxyConfig{
+		URLs:        cfg.Proxies.URLs,
+		BanDuration: cfg.Proxies.BanDuration,
+		MaxFailures: cfg.Proxies.MaxFailures,
+	})
+	if err != nil {
+		return ScraperConfig{}, fmt.Errorf("failed to configure proxies: %w", err)
+	}
+	switch cfg.ScraperAPI.Format {
+	case "", ScraperAPIFormatHTML, ScraperAPIFormatJSON:
+	default:
+		return ScraperConfig{}, fmt.Errorf("unknown scraperapi output_format %q", cfg.ScraperAPI.Format)
+	}
+
+	return ScraperConfig{
+		UserAgent:      cfg.UserAgent,
+		UserAgents:     cfg.UserAgents,
+		RotateHeaders:  cfg.RotateHeaders,
+		Proxies:        proxies,
+		RequestDelay:   cfg.RequestDelay,
+		RequestTimeout: cfg.RequestTimeout,
+		Workers:        cfg.Workers,
+		CheckInterval:  cfg.CheckInterval,
+		JobLease:       cfg.JobLease,
+		WorkerID:       cfg.WorkerID,
+		Rules:          rules,
+		ScraperAPI: ScraperAPIConfig{
+			APIKey:  cfg.ScraperAPI.APIKey,
+			Render:  cfg.ScraperAPI.Render,
+			Timeout: cfg.ScraperAPI.Timeout,
+			Format:  cfg.ScraperAPI.Format,
+		},
+		ScraperAPIHosts: cfg.ScraperAPI.Hosts,
+		Fetcher:         cfg.Fetcher,
+		Fetchers:        cfg.Fetchers,
+		FixtureDir:      cfg.FixtureDir,
+		CacheDir:        cfg.CacheDir,
+		CacheTTL:        cfg.CacheTTL,
+		Politeness:      politenessConfig(cfg.Politeness),
+		Retry: RetryPolicy{
+			MaxRetries: cfg.MaxRetries,
+			Delay:      cfg.RetryDelay,
+			MaxDelay:   cfg.MaxRetryDelay,
+		},
+		Robots: RobotsConfig{
+			Policy:    cfg.Robots.Policy,
+			UserAgent: cfg.Robots.UserAgent,
+			CacheTTL:  cfg.Robots.CacheTTL,
+		},
+		Offers: OfferPolicy{
+			Rule:           cfg.Offers.Rule,
+			TrustedSellers: cfg.Offers.TrustedSellers,
+		},
+		Adaptive: AdaptivePolicy{
+			Enabled:     cfg.Adaptive.Enabled,
+			MinInterval: cfg.Adaptive.MinInterval,
+			MaxInterval: cfg.Adaptive.MaxInterval,
+			Window:      cfg.Adaptive.Window,
+		},
+	}, nil
+}
+
+// politenessConfig converts the configured rate limits for the scraper
+func politenessConfig(cfg config.PolitenessConfig) PolitenessConfig {
+	policy := func(p config.HostPolicy) HostPolicy {
+		return HostPolicy{MaxConcurrent: p.MaxPerHost, MinInterval: p.MinInterval, Jitter: p.Jitter}
+	}
+	out := PolitenessConfig{
+		Default:    policy(cfg.HostPolicy),
+		Hosts:      make(map[string]HostPolicy, len(cfg.Hosts)),
+		MaxBackoff: cfg.MaxBackoff,
+	}
+	for host, p := range cfg.Hosts {
+		out.Hosts[host] = policy(p)
+	}
+	return out
+}
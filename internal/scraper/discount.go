@@ -0,0 +1,114 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+)
+
+// listPriceSelectors match the struck-through "was" price of common layouts
+var listPriceSelectors = []string{
+	".a-text-price .a-offscreen",    // Amazon
+	".andes-money-amount--previous", // Mercado Livre
+	"[class*='old-price']",
+	"[class*='oldPrice']",
+	"[class*='price-old']",
+	"[class*='list-price']",
+	"[class*='listPrice']",
+	"[class*='was-price']",
+	"[class*='preco-de']",
+	"del",
+	"s",
+}
+
+// listPriceFilter matches elements holding a list price, so that price
+// heuristics can skip them
+var listPriceFilter = strings.Join(listPriceSelectors, ", ")
+
+// discountSelectors match discount badges
+var discountSelectors = []string{
+	"[class*='discount']",
+	"[class*='desconto']",
+	"[class*='percent']",
+}
+
+// The page text runs adjacent elements together ("Fonede R$ 200,00"), so these
+// can't rely on word boundaries
+var (
+	// "de R$ 1.299,00 por R$ 999,00"
+	deRePorRe = regexp.MustCompile(`(?i)de:?\s*(R\$\s*[\d.,]+)\s*(?:por|para):?\s*R\$\s*[\d.,]+`)
+	// "25% OFF", "25% de desconto"
+	discountTextRe = regexp.MustCompile(`(?i)(\d{1,2}(?:[.,]\d{1,2})?)\s*%\s*(?:off|de desconto|desconto)`)
+	percentRe      = regexp.MustCompile(`(\d{1,2}(?:[.,]\d{1,2})?)\s*%`)
+)
+
+// isListPrice reports whether s is, or sits inside, a struck-through price
+func isListPrice(s *goquery.Selection) bool {
+	return s.Is(listPriceFilter) || s.ParentsFiltered(listPriceFilter).Length() > 0
+}
+
+// findListPrice looks for a struck-through price on the page
+func findListPrice(page *Page, opts price.Options) float64 {
+	for _, sel := range listPriceSelectors {
+		var found float64
+		page.Doc.Find(sel).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if amount, err := price.ParseWith(s.Text(), opts); err == nil {
+				found = amount.Value
+				return false
+			}
+			return true
+		})
+		if found > 0 {
+			return found
+		}
+	}
+	if m := deRePorRe.FindStringSubmatch(cleanText(page.Doc.Find("body").Text())); m != nil {
+		if amount, err := price.ParseWith(m[1], opts); err == nil {
+			return amount.Value
+		}
+	}
+	return 0
+}
+
+// findDiscount looks for an advertised discount percentage on the page
+func findDiscount(doc *goquery.Document) float64 {
+	var found float64
+	for _, sel := range discountSelectors {
+		doc.Find(sel).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			found, _ = parsePercent(s.Text())
+			return found == 0
+		})
+		if found > 0 {
+			return found
+		}
+	}
+	if m := discountTextRe.FindStringSubmatch(cleanText(doc.Find("body").Text())); m != nil {
+		found, _ = parsePercent(m[0])
+	}
+	return found
+}
+
+// parsePercent reads a percentage such as "-25%" or "12,5% OFF"
+func parsePercent(text string) (float64, bool) {
+	m := percentRe.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+	if err != nil || v <= 0 || v >= 100 {
+		return 0, false
+	}
+	return v, true
+}
+
+// discountPercent is the discount a list price implies, 0 unless it is above
+// the current price
+func discountPercent(listPrice, current float64) float64 {
+	if listPrice <= current || current <= 0 {
+		return 0
+	}
+	return price.Discount(listPrice, current)
+}
@@ -0,0 +1,97 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+)
+
+func TestFindListPrice(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want float64
+	}{
+		{name: "none", html: `<span class="price">R$ 999,00</span>`},
+		{name: "old price class", html: `<span class="product-old-price">R$ 1.299,00</span><span class="price">R$ 999,00</span>`, want: 1299},
+		{name: "struck through", html: `<del>R$ 1.199,90</del> <span class="price">R$ 999,00</span>`, want: 1199.9},
+		{name: "unparseable first match", html: `<s>antes</s><s>R$ 1.099,00</s>`, want: 1099},
+		{name: "de ... por", html: `<p>De R$ 1.499,00 por R$ 999,00 à vista</p>`, want: 1499},
+	}
+
+	u, _ := url.Parse("https://loja.example/p")
+	opts := price.Options{Locale: price.Comma, Currency: "BRL"}
+	for _, tt := range tests {
+		page, err := NewPage(u, []byte("<html><body>"+tt.html+"</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := findListPrice(page, opts); got != tt.want {
+			t.Errorf("%s: findListPrice = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindDiscount(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want float64
+	}{
+		{name: "none", html: `<span class="price">R$ 999,00</span>`},
+		{name: "badge", html: `<span class="discount-badge">-25%</span>`, want: 25},
+		{name: "badge without a number", html: `<span class="discount-badge">Oferta</span><span class="percent-off">12,5%</span>`, want: 12.5},
+		{name: "text", html: `<p>Aproveite: 30% de desconto no Pix</p>`, want: 30},
+		{name: "off", html: `<p>15% OFF</p>`, want: 15},
+		{name: "unrelated percentage", html: `<p>Juros de 2% ao mês</p>`},
+	}
+
+	u, _ := url.Parse("https://loja.example/p")
+	for _, tt := range tests {
+		page, err := NewPage(u, []byte("<html><body>"+tt.html+"</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := findDiscount(page.Doc); got != tt.want {
+			t.Errorf("%s: findDiscount = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  float64
+		found bool
+	}{
+		{"-25%", 25, true},
+		{"12,5% OFF", 12.5, true},
+		{"7.5 %", 7.5, true},
+		{"0%", 0, false},
+		{"sem desconto", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parsePercent(tt.in)
+		if got != tt.want || ok != tt.found {
+			t.Errorf("parsePercent(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func TestDiscountPercent(t *testing.T) {
+	tests := []struct {
+		list, price, want float64
+	}{
+		{2999, 2399, 20},
+		{100, 66.66, 33.3},
+		{100, 100, 0},
+		{90, 100, 0},
+		{100, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := discountPercent(tt.list, tt.price); got != tt.want {
+			t.Errorf("discountPercent(%v, %v) = %v, want %v", tt.list, tt.price, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rs/zerolog/log"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
+)
+
+// Page is a downloaded product page ready for extraction
+type Page struct {
+	URL  *url.URL
+	Doc  *goquery.Document
+	Body []byte
+}
+
+// NewPage parses the raw HTML of a product page
+func NewPage(pageURL *url.URL, body []byte) (*Page, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return &Page{URL: pageURL, Doc: doc, Body: body}, nil
+}
+
+// PriceOptions returns price parsing options matching the page's declared language
+func (p *Page) PriceOptions() price.Options {
+	var opts price.Options
+	if lang, ok := p.Doc.Find("html").Attr("lang"); ok {
+		opts.Locale, _ = price.LocaleFor(lang)
+	}
+	return opts
+}
+
+// Resolve turns a possibly relative reference found on the page into an absolute URL
+func (p *Page) Resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || p.URL == nil {
+		return ref
+	}
+	u, err := p.URL.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// Extraction holds the product fields an extractor managed to find.
+// Zero values mean "not found".
+type Extraction struct {
+	Name         string
+	Price        float64
+	OldPrice     float64 // struck-through "was" (list) price, when the page shows one
+	Discount     float64 // advertised discount percentage, e.g. 25 for "25% OFF"
+	Currency     string
+	ImageURL     string
+	Availability models.Availability
+	Shipping     *float64 // nil when the page doesn't show it; 0 means free shipping
+	Variants     []models.Variant
+	Offers       []models.Offer // one per seller, on marketplace pages
+
+	// PriceErr explains why a price label found on the page could not be parsed
+	PriceErr error
+
+	// Sources records which extractor produced each field (keyed by
+	// "name", "price", "old_price", "discount", "image", "availability", "shipping", "variants" and "offers"); filled in by Pipeline.Run
+	Sources map[string]string
+}
+
+// complete reports whether every field has been filled. Most products are not
+// on sale, so pages without a list price run through every extractor.
+func (e *Extraction) complete() bool {
+	return e.Name != "" && e.Price > 0 && e.Currency != "" && e.ImageURL != "" && e.Availability != "" && e.OldPrice > 0
+}
+
+// merge fills the fields of e that are still empty from other, crediting
+// source for each field it fills
+func (e *Extraction) merge(other *Extraction, source string) {
+	if e.Sources == nil {
+		e.Sources = make(map[string]string)
+	}
+	if e.Name == "" && other.Name != "" {
+		e.Name = other.Name
+		e.Sources["name"] = source
+	}
+	if e.Price <= 0 && other.Price > 0 {
+		e.Price = other.Price
+		e.Sources["price"] = source
+		e.PriceErr = nil
+		if other.Currency != "" {
+			e.Currency = other.Currency
+		}
+	}
+	if e.Price <= 0 && e.PriceErr == nil {
+		e.PriceErr = other.PriceErr
+	}
+	if e.OldPrice <= 0 && other.OldPrice > 0 {
+		e.OldPrice = other.OldPrice
+		e.Sources["old_price"] = source
+	}
+	if e.Discount <= 0 && other.Discount > 0 {
+		e.Discount = other.Discount
+		e.Sources["discount"] = source
+	}
+	if e.Currency == "" {
+		e.Currency = other.Currency
+	}
+	if e.ImageURL == "" && other.ImageURL != "" {
+		e.ImageURL = other.ImageURL
+		e.Sources["image"] = source
+	}
+	if e.Availability == "" && other.Availability != "" {
+		e.Availability = other.Availability
+		e.Sources["availability"] = source
+	}
+	if e.Shipping == nil && other.Shipping != nil {
+		e.Shipping = other.Shipping
+		e.Sources["shipping"] = source
+	}
+	if len(e.Variants) == 0 && len(other.Variants) > 0 {
+		e.Variants = other.Variants
+		e.Sources["variants"] = source
+	}
+	if len(e.Offers) == 0 && len(other.Offers) > 0 {
+		e.Offers = other.Offers
+		e.Sources["offers"] = source
+	}
+}
+
+// Extractor is a strategy for pulling product data out of a page.
+// Extractors return (nil, nil) when the page holds nothing they understand.
+type Extractor interface {
+	Name() string
+	Extract(page *Page) (*Extraction, error)
+}
+
+// Pipeline runs extractors in order, each one filling only the fields
+// earlier extractors left empty
+type Pipeline struct {
+	extractors []Extractor
+}
+
+// NewPipeline creates a pipeline from the given extractors, in priority order
+func NewPipeline(extractors ...Extractor) *Pipeline {
+	return &Pipeline{extractors: extractors}
+}
+
+// DefaultPipeline returns the extractors used for pages without site-specific handling
+func DefaultPipeline() *Pipeline {
+	return NewPipeline(defaultExtractors()...)
+}
+
+// RulesPipeline returns the default pipeline with rules consulted first
+func RulesPipeline(rules *siterules.Set) *Pipeline {
+	return NewPipeline(append([]Extractor{NewRuleExtractor(rules)}, defaultExtractors()...)...)
+}
+
+// defaultExtractors lists the generic extractors, most reliable first
+func defaultExtractors() []Extractor {
+	return []Extractor{
+		&JSONLDExtractor{},
+		&MicrodataExtractor{},
+		&MetaTagExtractor{},
+		&HeuristicExtractor{},
+	}
+}
+
+// Run extracts product data from page
+func (p *Pipeline) Run(page *Page) *Extraction {
+	result := &Extraction{}
+	for _, ex := range p.extractors {
+		found, err := ex.Extract(page)
+		if err != nil {
+			log.Debug().Err(err).Str("extractor", ex.Name()).Str("url", page.URL.String()).Msg("Extractor failed")
+			continue
+		}
+		if found == nil {
+			continue
+		}
+		result.merge(found, ex.Name())
+		if result.complete() {
+			break
+		}
+	}
+	return result
+}
+
+// cleanText collapses the whitespace of text scraped from a page
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
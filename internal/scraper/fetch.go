@@ -0,0 +1,231 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// FetchRequest describes a page to download
+type FetchRequest struct {
+	URL    string
+	Header http.Header // extra request headers, may be nil
+}
+
+// FetchResult is a downloaded page together with its response metadata
+type FetchResult struct {
+	URL        string // final URL, after redirects
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Fetcher    string // name of the Fetcher that produced the result
+	FetchedAt  time.Time
+	Duration   time.Duration
+}
+
+// Fetcher retrieves the raw HTML of product pages. Implementations decide how
+// (directly, through ScraperAPI, from local fixtures); extraction never cares.
+type Fetcher interface {
+	Name() string
+	Fetch(ctx context.Context, req *FetchRequest) (*FetchResult, error)
+}
+
+// StatusError is returned by fetchers when the store answers with an HTTP error.
+// Result holds the response so callers can still inspect headers and body.
+type StatusError struct {
+	StatusCode int
+	Result     *FetchResult
+}
+
+// Error implements error
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// checkStatus turns HTTP error responses into a *StatusError
+func checkStatus(res *FetchResult) (*FetchResult, error) {
+	if res.StatusCode >= 400 {
+		return res, &StatusError{StatusCode: res.StatusCode, Result: res}
+	}
+	return res, nil
+}
+
+// Fetcher names understood by PriceScraper
+const (
+	FetcherDirect     = "direct"
+	FetcherScraperAPI = "scraperapi"
+	FetcherFixture    = "fixture"
+)
+
+// DirectFetcher downloads pages from the store with colly, optionally through
+// a proxy pool and with rotating browser headers
+type DirectFetcher struct {
+	userAgent string
+	timeout   time.Duration
+	transport http.RoundTripper
+	headers   *HeaderRotator // nil sends userAgent only
+	proxies   *ProxyPool     // nil connects directly
+
+	// blockReason recognizes block pages served with status 200, so the proxy
+	// that got one is treated as banned
+	blockReason func(host string, res *FetchResult) string
+}
+
+// maxProxyAttempts bounds how many proxies a single fetch tries when stores
+// block them
+const maxProxyAttempts = 3
+
+// NewDirectFetcher creates a fetcher that talks to stores directly
+func NewDirectFetcher(userAgent string, timeout time.Duration) *DirectFetcher {
+	return &DirectFetcher{userAgent: userAgent, timeout: timeout, transport: http.DefaultTransport}
+}
+
+// Name implements Fetcher
+func (f *DirectFetcher) Name() string { return FetcherDirect }
+
+// Fetch implements Fetcher. With a proxy pool, a page the store refuses to
+// serve through one proxy is retried through the next.
+func (f *DirectFetcher) Fetch(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	if f.proxies.Len() == 0 {
+		return f.fetch(ctx, req, f.transport)
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	for attempt := 1; ; attempt++ {
+		px, err := f.proxies.pick(u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		res, err := f.fetch(ctx, req, px.transport)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		banned := res != nil && (isBan(res) || f.blockReason != nil && f.blockReason(u.Hostname(), res) != "")
+		f.proxies.report(px, u.Hostname(), res, err, banned)
+		if banned && attempt < maxProxyAttempts && attempt < f.proxies.Len() {
+			continue
+		}
+		return res, err
+	}
+}
+
+// fetch downloads req once over transport
+func (f *DirectFetcher) fetch(ctx context.Context, req *FetchRequest, transport http.RoundTripper) (*FetchResult, error) {
+	c := colly.NewCollector(colly.UserAgent(f.userAgent))
+	c.WithTransport(&contextTransport{ctx: ctx, base: transport})
+	if f.timeout > 0 {
+		c.SetRequestTimeout(f.timeout)
+	}
+	// We classify HTTP errors ourselves, and need the body of 4xx/5xx pages to do so
+	c.ParseHTTPErrorResponse = true
+
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		if f.headers != nil {
+			for k, v := range f.headers.Next().Header {
+				(*r.Headers)[k] = v
+			}
+		}
+		for k, v := range req.Header {
+			(*r.Headers)[k] = v
+		}
+	})
+
+	start := time.Now()
+	var res *FetchResult
+	c.OnResponse(func(r *colly.Response) {
+		res = &FetchResult{
+			URL:        r.Request.URL.String(),
+			StatusCode: r.StatusCode,
+			Header:     *r.Headers,
+			Body:       r.Body,
+			Fetcher:    FetcherDirect,
+			FetchedAt:  start,
+			Duration:   time.Since(start),
+		}
+	})
+
+	if err := c.Visit(req.URL); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to visit url: %w", err)
+	}
+	if res == nil {
+		return nil, errors.New("no response received")
+	}
+	return checkStatus(res)
+}
+
+// FixtureFetcher serves pages from local files, for offline development and tests.
+// https://store.com/p/123 is read from <dir>/store.com/p/123.html, and
+// https://store.com/ from <dir>/store.com/index.html. file:// URLs are read as-is.
+type FixtureFetcher struct {
+	dir string
+}
+
+// NewFixtureFetcher creates a fetcher that reads pages below dir
+func NewFixtureFetcher(dir string) *FixtureFetcher {
+	return &FixtureFetcher{dir: dir}
+}
+
+// Name implements Fetcher
+func (f *FixtureFetcher) Name() string { return FetcherFixture }
+
+// Fetch implements Fetcher
+func (f *FixtureFetcher) Fetch(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	filename, err := f.path(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res := &FetchResult{
+		URL:       req.URL,
+		Header:    http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Fetcher:   FetcherFixture,
+		FetchedAt: start,
+	}
+	res.Body, err = os.ReadFile(filename)
+	res.Duration = time.Since(start)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		res.StatusCode = http.StatusNotFound
+		return checkStatus(res)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	res.StatusCode = http.StatusOK
+	return res, nil
+}
+
+// path maps a URL to its fixture file
+func (f *FixtureFetcher) path(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme == "file" {
+		return u.Path, nil
+	}
+
+	p := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") || p == "/" {
+		p = path.Join(p, "index.html")
+	} else if path.Ext(p) == "" {
+		p += ".html"
+	}
+	return filepath.Join(f.dir, u.Hostname(), filepath.FromSlash(p)), nil
+}
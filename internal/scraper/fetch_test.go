@@ -0,0 +1,176 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+func TestFixtureFetcherPath(t *testing.T) {
+	f := NewFixtureFetcher("pages")
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://loja.example/p/123", filepath.Join("pages", "loja.example", "p", "123.html")},
+		{"https://loja.example/p/123.html", filepath.Join("pages", "loja.example", "p", "123.html")},
+		{"https://loja.example/", filepath.Join("pages", "loja.example", "index.html")},
+		{"https://loja.example", filepath.Join("pages", "loja.example", "index.html")},
+		{"https://loja.example/categoria/", filepath.Join("pages", "loja.example", "categoria", "index.html")},
+		{"https://loja.example:8443/p/1?ref=home", filepath.Join("pages", "loja.example", "p", "1.html")},
+		{"https://loja.example/../../etc/passwd", filepath.Join("pages", "loja.example", "etc", "passwd.html")},
+		{"file:///tmp/page.html", "/tmp/page.html"},
+	}
+	for _, tt := range tests {
+		got, err := f.path(tt.url)
+		if err != nil {
+			t.Errorf("path(%q) error: %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("path(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestFixtureFetcher(t *testing.T) {
+	f := NewFixtureFetcher(filepath.Join("testdata", "stores"))
+
+	res, err := f.Fetch(context.Background(), &FetchRequest{URL: "https://loja.example/fone-xyz"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || res.Fetcher != FetcherFixture || len(res.Body) == 0 {
+		t.Errorf("Fetch = status %d from %q with %d bytes", res.StatusCode, res.Fetcher, len(res.Body))
+	}
+
+	_, err = f.Fetch(context.Background(), &FetchRequest{URL: "https://loja.example/missing"})
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Errorf("Fetch of a missing fixture = %v, want a 404 StatusError", err)
+	}
+}
+
+func TestScrapeProductFromFixtures(t *testing.T) {
+	s := NewScraper(nil, ScraperConfig{
+		Fetcher:    FetcherFixture,
+		FixtureDir: filepath.Join("testdata", "stores"),
+	})
+	got, err := s.ScrapeProduct(context.Background(), &models.Product{URL: "https://loja.example/fone-xyz"})
+	if err != nil {
+		t.Fatalf("ScrapeProduct: %v", err)
+	}
+	if got.Name != "Fone Bluetooth XYZ" || got.CurrentPrice != 199.90 || got.Currency != "BRL" {
+		t.Errorf("ScrapeProduct = %q %v %q", got.Name, got.CurrentPrice, got.Currency)
+	}
+	if got.ImageURL != "https://loja.example/img/fone-xyz.jpg" {
+		t.Errorf("ImageURL = %q", got.ImageURL)
+	}
+}
+
+func TestDirectFetcher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "pricewatcher-test" {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte("<html><body>Produto removido</body></html>"))
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("<html><body><h1>" + r.Header.Get("X-Test") + "</h1></body></html>"))
+	}))
+	defer srv.Close()
+	f := NewDirectFetcher("pricewatcher-test", 0)
+
+	res, err := f.Fetch(context.Background(), &FetchRequest{URL: srv.URL + "/p/1", Header: http.Header{"X-Test": {"forwarded"}}})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || res.Fetcher != FetcherDirect || res.Header.Get("ETag") != `"v1"` {
+		t.Errorf("Fetch = status %d from %q, ETag %q", res.StatusCode, res.Fetcher, res.Header.Get("ETag"))
+	}
+	if string(res.Body) != "<html><body><h1>forwarded</h1></body></html>" {
+		t.Errorf("Body = %q", res.Body)
+	}
+
+	_, err = f.Fetch(context.Background(), &FetchRequest{URL: srv.URL + "/gone"})
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusGone {
+		t.Fatalf("Fetch = %v, want a 410 StatusError", err)
+	}
+	if se.Result == nil || string(se.Result.Body) != "<html><body>Produto removido</body></html>" {
+		t.Errorf("StatusError.Result does not hold the error page")
+	}
+}
+
+func TestScraperAPIErrorHidesKey(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // the transport fails to connect
+	c := NewScraperAPIClient(ScraperAPIConfig{APIKey: "s3cr3t-key", BaseURL: srv.URL})
+
+	_, err := c.Fetch(context.Background(), &FetchRequest{URL: "https://loja.example/p/1"})
+	if err == nil {
+		t.Fatal("Fetch against a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), "s3cr3t-key") {
+		t.Errorf("error leaks the api key: %v", err)
+	}
+	if !strings.Contains(err.Error(), "api_key=REDACTED") {
+		t.Errorf("error = %v, want the redacted request URL", err)
+	}
+}
+
+func TestFetcherFor(t *testing.T) {
+	s := NewScraper(nil, ScraperConfig{
+		ScraperAPI:      ScraperAPIConfig{APIKey: "key"},
+		ScraperAPIHosts: []string{"magazineluiza.com.br"},
+		Fetchers: map[string]string{
+			"loja.example":     FetcherFixture,
+			"api.loja.example": FetcherDirect,
+			"*.test":           FetcherFixture,
+		},
+		FixtureDir: "testdata",
+	})
+	tests := []struct {
+		name    string
+		product string // the product's own fetcher setting
+		host    string
+		want    string
+	}{
+		{"default", "", "store.example", FetcherDirect},
+		{"scraperapi host", "", "magazineluiza.com.br", FetcherScraperAPI},
+		{"scraperapi subdomain", "", "www.magazineluiza.com.br", FetcherScraperAPI},
+		{"host pattern", "", "loja.example", FetcherFixture},
+		{"most specific pattern wins", "", "api.loja.example", FetcherDirect},
+		{"glob pattern", "", "shop.test", FetcherFixture},
+		{"product setting wins", FetcherDirect, "magazineluiza.com.br", FetcherDirect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := s.fetcherFor(&models.Product{Fetcher: tt.product}, tt.host)
+			if err != nil {
+				t.Fatalf("fetcherFor: %v", err)
+			}
+			if f.Name() != tt.want {
+				t.Errorf("fetcherFor(%q, %q) = %q, want %q", tt.product, tt.host, f.Name(), tt.want)
+			}
+		})
+	}
+
+	// A fetcher that isn't set up is an error, not a silent fallback
+	s = NewScraper(nil, ScraperConfig{ScraperAPIHosts: []string{"magazineluiza.com.br"}})
+	if _, err := s.fetcherFor(&models.Product{}, "magazineluiza.com.br"); err == nil {
+		t.Error("fetcherFor chose an unconfigured fetcher")
+	}
+	if _, err := s.fetcherFor(&models.Product{Fetcher: "carrier-pigeon"}, "store.example"); err == nil {
+		t.Error("fetcherFor accepted an unknown fetcher")
+	}
+}
@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+)
+
+// HeaderProfile is a consistent set of request headers, as sent by one browser.
+// Mixing a Firefox User-Agent with Chrome's client hints is an easy way to be
+// flagged as a bot, so headers are always rotated together.
+type HeaderProfile struct {
+	Name   string
+	Header http.Header
+}
+
+// UserAgent returns the profile's User-Agent
+func (p HeaderProfile) UserAgent() string { return p.Header.Get("User-Agent") }
+
+// defaultProfiles imitate current desktop browsers
+var defaultProfiles = []HeaderProfile{
+	{Name: "chrome-windows", Header: http.Header{
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"},
+		"Accept-Language":           {"pt-BR,pt;q=0.9,en-US;q=0.8,en;q=0.7"},
+		"Sec-Ch-Ua":                 {`"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"Windows"`},
+		"Sec-Fetch-Dest":            {"document"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-Site":            {"none"},
+		"Upgrade-Insecure-Requests": {"1"},
+	}},
+	{Name: "chrome-macos", Header: http.Header{
+		"User-Agent":                {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"},
+		"Accept-Language":           {"en-US,en;q=0.9"},
+		"Sec-Ch-Ua":                 {`"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"macOS"`},
+		"Sec-Fetch-Dest":            {"document"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-Site":            {"none"},
+		"Upgrade-Insecure-Requests": {"1"},
+	}},
+	{Name: "firefox-windows", Header: http.Header{
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"},
+		"Accept-Language":           {"pt-BR,pt;q=0.8,en-US;q=0.5,en;q=0.3"},
+		"Sec-Fetch-Dest":            {"document"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-Site":            {"none"},
+		"Upgrade-Insecure-Requests": {"1"},
+	}},
+	{Name: "safari-macos", Header: http.Header{
+		"User-Agent":      {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15"},
+		"Accept":          {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+		"Accept-Language": {"en-US,en;q=0.9"},
+	}},
+	{Name: "edge-windows", Header: http.Header{
+		"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0"},
+		"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"},
+		"Accept-Language":           {"pt-BR,pt;q=0.9,en;q=0.8"},
+		"Sec-Ch-Ua":                 {`"Chromium";v="124", "Microsoft Edge";v="124", "Not-A.Brand";v="99"`},
+		"Sec-Ch-Ua-Mobile":          {"?0"},
+		"Sec-Ch-Ua-Platform":        {`"Windows"`},
+		"Sec-Fetch-Dest":            {"document"},
+		"Sec-Fetch-Mode":            {"navigate"},
+		"Sec-Fetch-Site":            {"none"},
+		"Upgrade-Insecure-Requests": {"1"},
+	}},
+}
+
+// HeaderRotator picks a random header profile for each request
+type HeaderRotator struct {
+	profiles []HeaderProfile
+}
+
+// NewHeaderRotator rotates through the given user agents, or through the
+// built-in browser profiles when none are given
+func NewHeaderRotator(userAgents []string) *HeaderRotator {
+	if len(userAgents) == 0 {
+		return &HeaderRotator{profiles: defaultProfiles}
+	}
+
+	r := &HeaderRotator{}
+	for _, ua := range userAgents {
+		r.profiles = append(r.profiles, profileFor(ua))
+	}
+	return r
+}
+
+// Next returns the profile to use for the next request
+func (r *HeaderRotator) Next() HeaderProfile {
+	return r.profiles[rand.Intn(len(r.profiles))]
+}
+
+// profileFor builds a profile around a configured user agent, borrowing the
+// other headers from the built-in profile of the same browser
+func profileFor(ua string) HeaderProfile {
+	lower := strings.ToLower(ua)
+	base := defaultProfiles[0]
+	switch {
+	case strings.Contains(lower, "edg/"):
+		base = defaultProfiles[4]
+	case strings.Contains(lower, "firefox/"):
+		base = defaultProfiles[2]
+	case strings.Contains(lower, "safari/") && !strings.Contains(lower, "chrome/"):
+		base = defaultProfiles[3]
+	}
+
+	header := base.Header.Clone()
+	header.Set("User-Agent", ua)
+	// The client hints carry a browser version we can't vouch for
+	header.Del("Sec-Ch-Ua")
+	header.Del("Sec-Ch-Ua-Mobile")
+	header.Del("Sec-Ch-Ua-Platform")
+	return HeaderProfile{Name: "custom", Header: header}
+}
@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+)
+
+// HeuristicExtractor guesses product fields from common page layouts.
+// It is the last resort when a page has no structured data.
+type HeuristicExtractor struct{}
+
+// Name implements Extractor
+func (h *HeuristicExtractor) Name() string { return "heuristic" }
+
+// priceSelectors are tried in order; the first parseable match wins
+var priceSelectors = []string{
+	"[data-price]",
+	"#priceblock_ourprice",
+	".a-price .a-offscreen",
+	".andes-money-amount",
+	".product-price",
+	".sales-price",
+	".preco",
+	".price",
+	"[class*='price']",
+}
+
+// imageSelectors are tried in order; the first match with a src wins
+var imageSelectors = []string{
+	"#landingImage",
+	".product-image img",
+	".gallery img",
+	"main img",
+}
+
+// discontinuedPhrases indicate that a product is no longer sold; they are
+// checked before outOfStockPhrases, which such pages often show too
+var discontinuedPhrases = []string{
+	"discontinued",
+	"no longer available",
+	"fora de linha",
+	"descontinuado",
+	"descatalogado",
+}
+
+// outOfStockPhrases indicate that a product cannot be bought right now
+var outOfStockPhrases = []string{
+	"out of stock",
+	"sold out",
+	"currently unavailable",
+	"esgotado",
+	"indisponível",
+	"produto indisponível",
+	"sem estoque",
+	"agotado",
+}
+
+// preorderPhrases indicate that a product can be ordered but hasn't shipped yet
+var preorderPhrases = []string{
+	"pre-order",
+	"preorder",
+	"pré-venda",
+	"pre-venda",
+	"preventa",
+}
+
+// offPageSelectors match page chrome and code whose text says nothing about
+// the product itself, e.g. "sold out" badges in a related-products sidebar
+const offPageSelectors = "nav, footer, aside, script, style, noscript"
+
+// Extract implements Extractor
+func (h *HeuristicExtractor) Extract(page *Page) (*Extraction, error) {
+	doc := page.Doc
+	ex := &Extraction{}
+
+	title := doc.Find("h1").First()
+	ex.Name = cleanText(title.Text())
+	if ex.Name == "" {
+		ex.Name = cleanText(doc.Find("title").First().Text())
+	}
+
+	opts := page.PriceOptions()
+	var priceEl *goquery.Selection
+	for _, sel := range priceSelectors {
+		doc.Find(sel).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if isListPrice(s) {
+				return true
+			}
+			text, ok := s.Attr("data-price")
+			if !ok {
+				text = s.Text()
+			}
+			amount, err := price.ParseWith(text, opts)
+			if err != nil {
+				if ex.PriceErr == nil {
+					ex.PriceErr = err
+				}
+				return true
+			}
+			ex.Price, ex.Currency = amount.Value, amount.Currency
+			ex.PriceErr = nil
+			priceEl = s
+			return false
+		})
+		if ex.Price > 0 {
+			break
+		}
+	}
+
+	if ex.Price > 0 {
+		ex.OldPrice = findListPrice(page, opts)
+		ex.Discount = findDiscount(doc)
+	}
+
+	for _, sel := range imageSelectors {
+		img := doc.Find(sel).First()
+		src, ok := img.Attr("data-src")
+		if !ok {
+			src, ok = img.Attr("src")
+		}
+		if ok && src != "" {
+			ex.ImageURL = page.Resolve(src)
+			break
+		}
+	}
+
+	body := strings.ToLower(cleanText(productArea(doc, priceEl, title).Text()))
+	for _, check := range []struct {
+		phrases []string
+		state   models.Availability
+	}{
+		{discontinuedPhrases, models.AvailabilityDiscontinued},
+		{outOfStockPhrases, models.AvailabilityOutOfStock},
+		{preorderPhrases, models.AvailabilityPreorder},
+	} {
+		if containsAny(body, check.phrases) {
+			ex.Availability = check.state
+			break
+		}
+	}
+	if ex.Availability == "" && ex.Price > 0 {
+		ex.Availability = models.AvailabilityInStock
+	}
+
+	return ex, nil
+}
+
+// productArea returns a copy of the part of the page describing the product,
+// without offPageSelectors: the closest container holding both the price
+// element and the title, or the whole body when there is none
+func productArea(doc *goquery.Document, priceEl, title *goquery.Selection) *goquery.Selection {
+	area := doc.Find("body")
+	if priceEl != nil && title.Length() > 0 {
+		for p := priceEl.Parent(); p.Length() > 0 && !p.Is("body, html"); p = p.Parent() {
+			if p.Contains(title.Get(0)) {
+				area = p
+				break
+			}
+		}
+	}
+	area = area.Clone()
+	area.Find(offPageSelectors).Remove()
+	return area
+}
+
+// containsAny reports whether s contains any of phrases
+func containsAny(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+	return false
+}
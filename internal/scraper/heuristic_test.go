@@ -0,0 +1,116 @@
+package scraper
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+func TestHeuristicExtractor(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		url          string
+		name         string
+		price        float64
+		oldPrice     float64
+		discount     float64
+		currency     string
+		image        string
+		availability models.Availability
+	}{
+		{
+			fixture:      "heuristic_product.html",
+			url:          "https://loja.example/tv-50",
+			name:         `Smart TV 50" 4K`,
+			price:        2399,
+			oldPrice:     2999,
+			discount:     20,
+			currency:     "BRL",
+			image:        "https://loja.example/img/tv-50.jpg",
+			availability: models.AvailabilityInStock,
+		},
+		{
+			fixture:      "heuristic_out_of_stock.html",
+			url:          "https://shop.example/lamps/desk",
+			name:         "Desk Lamp",
+			image:        "https://shop.example/lamps/lamp.png",
+			availability: models.AvailabilityOutOfStock,
+		},
+		{
+			fixture:      "heuristic_noisy_product.html",
+			url:          "https://loja.example/tv-50",
+			name:         `Smart TV 50" 4K`,
+			price:        2399,
+			oldPrice:     2999,
+			discount:     20,
+			currency:     "BRL",
+			image:        "https://loja.example/img/tv-50.jpg",
+			availability: models.AvailabilityInStock,
+		},
+		{
+			fixture:      "heuristic_noisy_out_of_stock.html",
+			url:          "https://shop.example/lamps/desk",
+			name:         "Desk Lamp",
+			image:        "https://shop.example/lamps/lamp.png",
+			availability: models.AvailabilityOutOfStock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, _ := url.Parse(tt.url)
+			page, err := NewPage(u, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := (&HeuristicExtractor{}).Extract(page)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if got.Name != tt.name {
+				t.Errorf("Name = %q, want %q", got.Name, tt.name)
+			}
+			if got.Price != tt.price || got.Currency != tt.currency {
+				t.Errorf("Price = %v %q, want %v %q", got.Price, got.Currency, tt.price, tt.currency)
+			}
+			if got.OldPrice != tt.oldPrice || got.Discount != tt.discount {
+				t.Errorf("OldPrice, Discount = %v, %v, want %v, %v", got.OldPrice, got.Discount, tt.oldPrice, tt.discount)
+			}
+			if got.ImageURL != tt.image {
+				t.Errorf("ImageURL = %q, want %q", got.ImageURL, tt.image)
+			}
+			if got.Availability != tt.availability {
+				t.Errorf("Availability = %q, want %q", got.Availability, tt.availability)
+			}
+		})
+	}
+}
+
+func TestPipelineSources(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "heuristic_product.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://loja.example/tv-50")
+	page, err := NewPage(u, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := DefaultPipeline().Run(page)
+	if got.Price != 2399 || got.OldPrice != 2999 {
+		t.Fatalf("Price = %v, OldPrice = %v, want 2399 and 2999", got.Price, got.OldPrice)
+	}
+	for field, want := range map[string]string{"price": "heuristic", "old_price": "heuristic", "discount": "heuristic", "availability": "heuristic"} {
+		if got.Sources[field] != want {
+			t.Errorf("Sources[%q] = %q, want %q", field, got.Sources[field], want)
+		}
+	}
+}
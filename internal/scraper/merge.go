@@ -0,0 +1,49 @@
+package scraper
+
+import (
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+// Change describes what merging one scrape changed on a tracked product
+type Change struct {
+	Old          models.Product // the product as it was before the merge
+	PriceChanged bool           // price, shipping or list price
+	StockChanged bool
+	Started      time.Time // when the check that produced the scrape began; zero outside Check
+}
+
+// Merge applies a scrape onto the tracked product. Scrape results carry a
+// fresh ID and none of the user's settings, so only the observed fields are
+// copied: the product keeps its identity, fetcher and pinned variant. A
+// scrape without a price (out of stock pages) keeps the last known price.
+func Merge(product, scraped *models.Product) *Change {
+	change := &Change{Old: *product}
+	if scraped.Name != "" {
+		product.Name = scraped.Name
+	}
+	if scraped.ImageURL != "" {
+		product.ImageURL = scraped.ImageURL
+	}
+	if scraped.CurrentPrice > 0 {
+		product.CurrentPrice = scraped.CurrentPrice
+		product.Currency = scraped.Currency
+		product.PriceSource = scraped.PriceSource
+		product.ShippingCost = scraped.ShippingCost
+		product.FreeShipping = scraped.FreeShipping
+		product.ListPrice = scraped.ListPrice
+		product.DiscountPercent = scraped.DiscountPercent
+	}
+	product.SetAvailability(scraped.Availability)
+	product.Variants = scraped.Variants
+	product.Website = scraped.Website
+	product.ScrapeStatus = models.ScrapeStatusOK
+	product.ETag = scraped.ETag
+	product.LastModified = scraped.LastModified
+
+	old := &change.Old
+	change.PriceChanged = product.CurrentPrice != old.CurrentPrice || product.ShippingCost != old.ShippingCost || product.ListPrice != old.ListPrice
+	change.StockChanged = product.Availability != old.Availability
+	return change
+}
@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+func TestMerge(t *testing.T) {
+	tracked := func() *models.Product {
+		p := &models.Product{
+			ID:           uuid.New(),
+			Name:         "Fone XYZ",
+			URL:          "https://loja.example/fone-xyz",
+			ImageURL:     "https://loja.example/fone.jpg",
+			CurrentPrice: 199.9,
+			Currency:     "BRL",
+			ShippingCost: 15,
+			ListPrice:    249.9,
+			VariantSKU:   "XYZ-PT",
+			Fetcher:      "scraperapi",
+			ScrapeStatus: models.ScrapeStatusBlocked,
+		}
+		p.SetAvailability(models.AvailabilityInStock)
+		return p
+	}
+	scrape := func(edit func(*models.Product)) *models.Product {
+		s := &models.Product{
+			ID:           uuid.New(),
+			Name:         "Fone XYZ",
+			ImageURL:     "https://loja.example/fone.jpg",
+			CurrentPrice: 199.9,
+			Currency:     "BRL",
+			ShippingCost: 15,
+			ListPrice:    249.9,
+			Availability: models.AvailabilityInStock,
+		}
+		if edit != nil {
+			edit(s)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name         string
+		scraped      *models.Product
+		price        float64
+		availability models.Availability
+		priceChanged bool
+		stockChanged bool
+	}{
+		{name: "nothing changed", scraped: scrape(nil), price: 199.9, availability: models.AvailabilityInStock},
+		{
+			name:         "price drop",
+			scraped:      scrape(func(s *models.Product) { s.CurrentPrice = 179.9 }),
+			price:        179.9,
+			availability: models.AvailabilityInStock,
+			priceChanged: true,
+		},
+		{
+			name:         "shipping change",
+			scraped:      scrape(func(s *models.Product) { s.ShippingCost, s.FreeShipping = 0, true }),
+			price:        199.9,
+			availability: models.AvailabilityInStock,
+			priceChanged: true,
+		},
+		{
+			name:         "list price change",
+			scraped:      scrape(func(s *models.Product) { s.ListPrice = 299.9 }),
+			price:        199.9,
+			availability: models.AvailabilityInStock,
+			priceChanged: true,
+		},
+		{
+			name:         "out of stock page keeps the last price",
+			scraped:      scrape(func(s *models.Product) { s.CurrentPrice, s.Currency, s.Availability = 0, "", models.AvailabilityOutOfStock }),
+			price:        199.9,
+			availability: models.AvailabilityOutOfStock,
+			stockChanged: true,
+		},
+		{
+			name:         "page without a stock state",
+			scraped:      scrape(func(s *models.Product) { s.Availability = "" }),
+			price:        199.9,
+			availability: models.AvailabilityUnknown,
+			stockChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		product := tracked()
+		id := product.ID
+		change := Merge(product, tt.scraped)
+
+		if change.PriceChanged != tt.priceChanged || change.StockChanged != tt.stockChanged {
+			t.Errorf("%s: PriceChanged, StockChanged = %v, %v, want %v, %v",
+				tt.name, change.PriceChanged, change.StockChanged, tt.priceChanged, tt.stockChanged)
+		}
+		if change.Old.CurrentPrice != 199.9 || change.Old.Availability != models.AvailabilityInStock {
+			t.Errorf("%s: Old = %v %s, want the product before the merge", tt.name, change.Old.CurrentPrice, change.Old.Availability)
+		}
+		if product.CurrentPrice != tt.price || product.Currency != "BRL" || product.Availability != tt.availability {
+			t.Errorf("%s: product = %v %s %s, want %v BRL %s",
+				tt.name, product.CurrentPrice, product.Currency, product.Availability, tt.price, tt.availability)
+		}
+		if product.IsAvailable != tt.availability.Purchasable() {
+			t.Errorf("%s: IsAvailable = %v out of step with %s", tt.name, product.IsAvailable, product.Availability)
+		}
+		// The user's settings survive the merge
+		if product.ID != id || product.URL != "https://loja.example/fone-xyz" || product.VariantSKU != "XYZ-PT" || product.Fetcher != "scraperapi" {
+			t.Errorf("%s: Merge overwrote the product's settings: %+v", tt.name, product)
+		}
+		if product.ScrapeStatus != models.ScrapeStatusOK {
+			t.Errorf("%s: ScrapeStatus = %q, want %q", tt.name, product.ScrapeStatus, models.ScrapeStatusOK)
+		}
+	}
+}
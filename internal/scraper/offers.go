@@ -0,0 +1,237 @@
+package scraper
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rs/zerolog/log"
+)
+
+// Offer rules, deciding which seller's offer sets a marketplace product's price
+const (
+	OfferRuleLowestNew     = "lowest_new"     // cheapest new (or unlabelled) offer in stock
+	OfferRuleLowestTrusted = "lowest_trusted" // cheapest new offer in stock from OfferPolicy.TrustedSellers
+	OfferRuleBuyBox        = "buy_box"        // the offer the page sells by default
+)
+
+// OfferPolicy controls how CurrentPrice is derived from a page's offers
+type OfferPolicy struct {
+	Rule           string   // one of the OfferRule* values; "" keeps the page's own price
+	TrustedSellers []string // matched ignoring case
+}
+
+// ldSeller reads a schema.org seller (Organization, Person or plain name)
+func ldSeller(v any) string {
+	if nodes := ldNodes(v); len(nodes) > 0 {
+		return cleanText(ldString(nodes[0]["name"]))
+	}
+	return cleanText(ldString(v))
+}
+
+// ldShipping reads the shipping rate of an offer's shippingDetails, or nil
+// when there is none
+func ldShipping(v any) *float64 {
+	for _, details := range ldNodes(v) {
+		for _, rate := range ldNodes(details["shippingRate"]) {
+			// Unlike prices, a zero rate is meaningful: free shipping
+			v := rate["value"]
+			if s, ok := v.(string); ok {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+					v = f
+				}
+			}
+			if f, ok := v.(float64); ok && f >= 0 {
+				return &f
+			}
+			if value, ok := ldPrice(v); ok {
+				return &value
+			}
+		}
+	}
+	return nil
+}
+
+// jsonLDOffers turns the offers of a product into seller offers when they
+// come from more than one seller, as on marketplace pages
+func jsonLDOffers(offers []ldOffer) []models.Offer {
+	var out []models.Offer
+	sellers := make(map[string]bool)
+	for _, o := range offers {
+		if o.seller == "" {
+			continue
+		}
+		sellers[strings.ToLower(o.seller)] = true
+		offer := models.Offer{
+			Seller:      o.seller,
+			Price:       o.price,
+			Currency:    o.currency,
+			Condition:   o.condition,
+			IsAvailable: o.stock == "" || o.stock.Purchasable(),
+		}
+		if o.shipping != nil {
+			offer.Shipping, offer.FreeShipping = *o.shipping, *o.shipping == 0
+		}
+		out = append(out, offer)
+	}
+	if len(sellers) < 2 {
+		return nil
+	}
+	return out
+}
+
+// ruleOffers reads the offers listed on page with a rule's offer selectors
+func ruleOffers(page *Page, cfg *siterules.Offers) []models.Offer {
+	if cfg.Item == "" {
+		return nil
+	}
+	opts := page.PriceOptions()
+	var out []models.Offer
+	page.Doc.Find(cfg.Item).Each(func(_ int, item *goquery.Selection) {
+		text, ok := cfg.Price.FindIn(item)
+		if !ok {
+			return
+		}
+		amount, err := price.ParseWith(text, opts)
+		if err != nil {
+			return
+		}
+		o := models.Offer{Price: amount.Value, Currency: amount.Currency, IsAvailable: true}
+		o.Seller, _ = cfg.Seller.FindIn(item)
+		if text, ok := cfg.Shipping.FindIn(item); ok {
+			if shipping, ok := parseShipping(text, opts); ok {
+				o.Shipping, o.FreeShipping = shipping, shipping == 0
+			}
+		}
+		if text, ok := cfg.Condition.FindIn(item); ok {
+			o.Condition = offerCondition(text)
+		}
+		o.Fulfillment, _ = cfg.Fulfillment.FindIn(item)
+		if cfg.BuyBox != "" {
+			o.BuyBox = item.Is(cfg.BuyBox) || item.Find(cfg.BuyBox).Length() > 0
+		}
+		out = append(out, o)
+	})
+	return out
+}
+
+// parseShipping reads a shipping cost label, where "frete grátis" means zero
+func parseShipping(text string, opts price.Options) (float64, bool) {
+	lower := strings.ToLower(text)
+	for _, free := range []string{"grátis", "gratis", "free"} {
+		if strings.Contains(lower, free) {
+			return 0, true
+		}
+	}
+	amount, err := price.ParseWith(text, opts)
+	if err != nil {
+		return 0, false
+	}
+	return amount.Value, true
+}
+
+// offerCondition maps a schema.org itemCondition or a condition label to one
+// of the models.OfferCondition* values
+func offerCondition(s string) string {
+	s = strings.ToLower(s)
+	switch {
+	case s == "":
+		return ""
+	case strings.Contains(s, "refurbished"), strings.Contains(s, "recondicionado"),
+		strings.Contains(s, "renewed"), strings.Contains(s, "reacondicionado"):
+		return models.OfferConditionRefurbished
+	case strings.Contains(s, "used"), strings.Contains(s, "usado"), strings.Contains(s, "damaged"):
+		return models.OfferConditionUsed
+	case strings.Contains(s, "new"), strings.Contains(s, "novo"), strings.Contains(s, "nuevo"):
+		return models.OfferConditionNew
+	}
+	return ""
+}
+
+// pick returns the offer the policy prices the product by, or nil when the
+// page's own price should be kept
+func (p OfferPolicy) pick(offers []models.Offer) *models.Offer {
+	var best *models.Offer
+	for i := range offers {
+		o := &offers[i]
+		if !o.IsAvailable || o.Price <= 0 {
+			continue
+		}
+		switch p.Rule {
+		case OfferRuleBuyBox:
+			if o.BuyBox {
+				return o
+			}
+			continue
+		case OfferRuleLowestTrusted:
+			if !p.trusts(o.Seller) {
+				continue
+			}
+		}
+		// An unlabelled offer is assumed to be new, as marketplaces label the exceptions
+		if o.Condition != "" && o.Condition != models.OfferConditionNew {
+			continue
+		}
+		if best == nil || o.Price < best.Price {
+			best = o
+		}
+	}
+	return best
+}
+
+// trusts reports whether seller is one of the trusted sellers
+func (p OfferPolicy) trusts(seller string) bool {
+	for _, trusted := range p.TrustedSellers {
+		if strings.EqualFold(strings.TrimSpace(trusted), strings.TrimSpace(seller)) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOffers replaces the page-level price of scraped with the offer picked
+// by the policy. Pages without a matching offer keep their own price.
+func applyOffers(policy OfferPolicy, scraped *models.Product) {
+	if policy.Rule == "" || len(scraped.Offers) == 0 {
+		return
+	}
+	o := policy.pick(scraped.Offers)
+	if o == nil {
+		log.Debug().Str("url", scraped.URL).Str("rule", policy.Rule).Msg("No offer matches the offer rule, keeping the page price")
+		return
+	}
+	scraped.CurrentPrice = o.Price
+	if o.Currency != "" {
+		scraped.Currency = o.Currency
+	}
+	scraped.ShippingCost, scraped.FreeShipping = o.Shipping, o.FreeShipping
+	scraped.SetAvailability(models.AvailabilityInStock)
+}
+
+// offersChanged reports whether the offers of a check differ from the last
+// recorded ones
+func offersChanged(last []*models.Offer, offers []models.Offer) bool {
+	if len(last) != len(offers) {
+		return true
+	}
+	type key struct {
+		seller, condition                 string
+		price, shipping                   float64
+		freeShipping, buyBox, isAvailable bool
+	}
+	seen := make(map[key]int, len(last))
+	for _, o := range last {
+		seen[key{o.Seller, o.Condition, o.Price, o.Shipping, o.FreeShipping, o.BuyBox, o.IsAvailable}]++
+	}
+	for _, o := range offers {
+		k := key{o.Seller, o.Condition, o.Price, o.Shipping, o.FreeShipping, o.BuyBox, o.IsAvailable}
+		if seen[k] == 0 {
+			return true
+		}
+		seen[k]--
+	}
+	return false
+}
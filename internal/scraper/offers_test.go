@@ -0,0 +1,199 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+)
+
+func TestOfferPolicyPick(t *testing.T) {
+	offers := []models.Offer{
+		{Seller: "Loja Oficial", Price: 1899, Condition: models.OfferConditionNew, IsAvailable: true},
+		{Seller: "Revenda X", Price: 1799, IsAvailable: true, BuyBox: true},
+		{Seller: "Usados BR", Price: 1299, Condition: models.OfferConditionUsed, IsAvailable: true},
+		{Seller: "Renova", Price: 1499, Condition: models.OfferConditionRefurbished, IsAvailable: true},
+		{Seller: "Esgotada", Price: 1599, Condition: models.OfferConditionNew},
+		{Seller: "Sem Preço", IsAvailable: true},
+	}
+
+	tests := []struct {
+		name   string
+		policy OfferPolicy
+		offers []models.Offer
+		want   string // seller of the picked offer, "" for none
+	}{
+		{name: "lowest new skips used, refurbished and out of stock", policy: OfferPolicy{Rule: OfferRuleLowestNew}, offers: offers, want: "Revenda X"},
+		{name: "lowest trusted", policy: OfferPolicy{Rule: OfferRuleLowestTrusted, TrustedSellers: []string{" loja oficial "}}, offers: offers, want: "Loja Oficial"},
+		{name: "lowest trusted ignores used trusted sellers", policy: OfferPolicy{Rule: OfferRuleLowestTrusted, TrustedSellers: []string{"Usados BR"}}, offers: offers},
+		{name: "lowest trusted without trusted sellers", policy: OfferPolicy{Rule: OfferRuleLowestTrusted}, offers: offers},
+		{name: "buy box", policy: OfferPolicy{Rule: OfferRuleBuyBox}, offers: offers, want: "Revenda X"},
+		{
+			name:   "buy box out of stock",
+			policy: OfferPolicy{Rule: OfferRuleBuyBox},
+			offers: []models.Offer{{Seller: "A", Price: 10, IsAvailable: true}, {Seller: "B", Price: 9, BuyBox: true}},
+		},
+		{name: "no offers", policy: OfferPolicy{Rule: OfferRuleLowestNew}},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if o := tt.policy.pick(tt.offers); o != nil {
+			got = o.Seller
+		}
+		if got != tt.want {
+			t.Errorf("%s: pick = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOffers(t *testing.T) {
+	offers := []models.Offer{
+		{Seller: "A", Price: 120, Currency: "BRL", IsAvailable: true},
+		{Seller: "B", Price: 95, Shipping: 15, Currency: "BRL", IsAvailable: true},
+		{Seller: "C", Price: 105, Currency: "BRL", FreeShipping: true, IsAvailable: true, BuyBox: true},
+	}
+
+	tests := []struct {
+		name      string
+		rule      string
+		offers    []models.Offer
+		price     float64
+		shipping  float64
+		free      bool
+		currency  string
+		available bool
+	}{
+		{name: "no rule keeps the page price", offers: offers, price: 130, shipping: 5, currency: "USD"},
+		{name: "no offers keeps the page price", rule: OfferRuleLowestNew, price: 130, shipping: 5, currency: "USD"},
+		{name: "no matching offer keeps the page price", rule: OfferRuleBuyBox, offers: offers[:2], price: 130, shipping: 5, currency: "USD"},
+		{name: "lowest new", rule: OfferRuleLowestNew, offers: offers, price: 95, shipping: 15, currency: "BRL", available: true},
+		{name: "free shipping", rule: OfferRuleBuyBox, offers: offers, price: 105, free: true, currency: "BRL", available: true},
+	}
+
+	for _, tt := range tests {
+		scraped := &models.Product{CurrentPrice: 130, ShippingCost: 5, Currency: "USD", Offers: tt.offers}
+		applyOffers(OfferPolicy{Rule: tt.rule}, scraped)
+		if scraped.CurrentPrice != tt.price || scraped.ShippingCost != tt.shipping || scraped.FreeShipping != tt.free ||
+			scraped.Currency != tt.currency || scraped.IsAvailable != tt.available {
+			t.Errorf("%s: got %v + %v (free=%v) %s available=%v, want %v + %v (free=%v) %s available=%v",
+				tt.name, scraped.CurrentPrice, scraped.ShippingCost, scraped.FreeShipping, scraped.Currency, scraped.IsAvailable,
+				tt.price, tt.shipping, tt.free, tt.currency, tt.available)
+		}
+	}
+}
+
+func TestOfferCondition(t *testing.T) {
+	tests := map[string]string{
+		"":                                "",
+		"https://schema.org/NewCondition": models.OfferConditionNew,
+		"Novo":                            models.OfferConditionNew,
+		"UsedCondition":                   models.OfferConditionUsed,
+		"Usado - bom estado":              models.OfferConditionUsed,
+		"DamagedCondition":                models.OfferConditionUsed,
+		"RefurbishedCondition":            models.OfferConditionRefurbished,
+		"Recondicionado":                  models.OfferConditionRefurbished,
+		"Renewed":                         models.OfferConditionRefurbished,
+		"Open box":                        "",
+	}
+	for in, want := range tests {
+		if got := offerCondition(in); got != want {
+			t.Errorf("offerCondition(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseShipping(t *testing.T) {
+	opts := price.Options{Locale: price.Comma, Currency: "BRL"}
+	tests := []struct {
+		in    string
+		want  float64
+		found bool
+	}{
+		{"Frete grátis", 0, true},
+		{"FREE delivery", 0, true},
+		{"+ R$ 19,90 de frete", 19.9, true},
+		{"Calcule o frete", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseShipping(tt.in, opts)
+		if got != tt.want || ok != tt.found {
+			t.Errorf("parseShipping(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func TestLdShipping(t *testing.T) {
+	rate := func(value any) any {
+		return map[string]any{"@type": "OfferShippingDetails", "shippingRate": map[string]any{"@type": "MonetaryAmount", "value": value, "currency": "BRL"}}
+	}
+	tests := []struct {
+		name    string
+		details any
+		want    float64
+		found   bool
+	}{
+		{name: "none", details: nil},
+		{name: "number", details: rate(19.9), want: 19.9, found: true},
+		{name: "string", details: rate("12.50"), want: 12.5, found: true},
+		{name: "free", details: rate(0.0), want: 0, found: true},
+		{name: "free as string", details: rate("0"), want: 0, found: true},
+		{name: "first of several", details: []any{rate(9.9), rate(29.9)}, want: 9.9, found: true},
+		{name: "no rate", details: map[string]any{"@type": "OfferShippingDetails"}},
+	}
+	for _, tt := range tests {
+		got := ldShipping(tt.details)
+		if (got != nil) != tt.found || got != nil && *got != tt.want {
+			t.Errorf("%s: ldShipping = %v, want %v (found %v)", tt.name, got, tt.want, tt.found)
+		}
+	}
+}
+
+func TestOffersChanged(t *testing.T) {
+	last := []*models.Offer{
+		{Seller: "A", Price: 100, IsAvailable: true},
+		{Seller: "B", Price: 90, Shipping: 10, IsAvailable: true, BuyBox: true},
+	}
+
+	tests := []struct {
+		name   string
+		offers []models.Offer
+		want   bool
+	}{
+		{
+			name:   "same offers in another order",
+			offers: []models.Offer{{Seller: "B", Price: 90, Shipping: 10, IsAvailable: true, BuyBox: true}, {Seller: "A", Price: 100, IsAvailable: true}},
+		},
+		{
+			name:   "price change",
+			offers: []models.Offer{{Seller: "A", Price: 99, IsAvailable: true}, {Seller: "B", Price: 90, Shipping: 10, IsAvailable: true, BuyBox: true}},
+			want:   true,
+		},
+		{
+			name:   "buy box moved",
+			offers: []models.Offer{{Seller: "A", Price: 100, IsAvailable: true, BuyBox: true}, {Seller: "B", Price: 90, Shipping: 10, IsAvailable: true}},
+			want:   true,
+		},
+		{
+			name:   "shipping became free",
+			offers: []models.Offer{{Seller: "A", Price: 100, IsAvailable: true, FreeShipping: true}, {Seller: "B", Price: 90, Shipping: 10, IsAvailable: true, BuyBox: true}},
+			want:   true,
+		},
+		{
+			name:   "seller left",
+			offers: []models.Offer{{Seller: "A", Price: 100, IsAvailable: true}},
+			want:   true,
+		},
+		{
+			name:   "duplicate replaces another seller",
+			offers: []models.Offer{{Seller: "A", Price: 100, IsAvailable: true}, {Seller: "A", Price: 100, IsAvailable: true}},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := offersChanged(last, tt.offers); got != tt.want {
+			t.Errorf("%s: offersChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+// MetaTagExtractor reads OpenGraph, product:* and Twitter card meta tags.
+// It is the fallback for stores that publish no schema.org data.
+type MetaTagExtractor struct{}
+
+// Name implements Extractor
+func (m *MetaTagExtractor) Name() string { return "opengraph" }
+
+// Extract implements Extractor
+func (m *MetaTagExtractor) Extract(page *Page) (*Extraction, error) {
+	meta := metaTags(page.Doc)
+	if len(meta) == 0 {
+		return nil, nil
+	}
+
+	ex := &Extraction{
+		Name:     cleanText(firstMeta(meta, "og:title", "twitter:title")),
+		ImageURL: page.Resolve(firstMeta(meta, "og:image:secure_url", "og:image", "og:image:url", "twitter:image")),
+		Currency: strings.ToUpper(firstMeta(meta, "product:price:currency", "og:price:currency", "product:sale_price:currency")),
+	}
+	for _, key := range []string{"product:sale_price:amount", "product:price:amount", "og:price:amount"} {
+		if value, ok := ldPrice(meta[key]); ok {
+			ex.Price = value
+			break
+		}
+	}
+	// With a sale price, the regular price is the one struck through
+	if regular, ok := ldPrice(firstMeta(meta, "product:original_price:amount", "product:price:amount")); ok && regular > ex.Price {
+		ex.OldPrice = regular
+	}
+	ex.Availability = metaAvailability(firstMeta(meta, "product:availability", "og:availability"))
+	return ex, nil
+}
+
+// metaTags collects <meta property|name=... content=...> pairs, keeping the first
+// occurrence of each key
+func metaTags(doc *goquery.Document) map[string]string {
+	out := make(map[string]string)
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		key, ok := s.Attr("property")
+		if !ok {
+			key, ok = s.Attr("name")
+		}
+		if !ok {
+			return
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if _, seen := out[key]; !seen && content != "" {
+			out[key] = content
+		}
+	})
+	return out
+}
+
+// firstMeta returns the value of the first key present in meta
+func firstMeta(meta map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := meta[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// metaAvailability understands both the free-text values Facebook documents
+// ("in stock", "oos") and schema.org URIs
+func metaAvailability(v string) models.Availability {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "":
+		return ""
+	case "instock", "in stock", "available for order":
+		return models.AvailabilityInStock
+	case "preorder", "pre-order":
+		return models.AvailabilityPreorder
+	case "oos", "out of stock", "pending":
+		return models.AvailabilityOutOfStock
+	case "discontinued":
+		return models.AvailabilityDiscontinued
+	}
+	return ldAvailability(v)
+}
@@ -0,0 +1,206 @@
+package scraper
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
+)
+
+// HostPolicy limits how hard a single store is hit
+type HostPolicy struct {
+	MaxConcurrent int           // requests in flight at once; defaults to 1
+	MinInterval   time.Duration // between the start of two requests
+	Jitter        time.Duration // random extra wait on top of MinInterval
+}
+
+// PolitenessConfig holds the per-host rate limits
+type PolitenessConfig struct {
+	Default    HostPolicy
+	Hosts      map[string]HostPolicy // host pattern -> policy, overriding Default
+	MaxBackoff time.Duration         // cap on the slowdown after 429/503; defaults to 30m
+}
+
+// defaultMaxBackoff is used when PolitenessConfig.MaxBackoff is not set
+const defaultMaxBackoff = 30 * time.Minute
+
+// limiter hands out per-host request slots. It enforces the host's concurrency
+// limit and interval, and backs off when the store says we are going too fast.
+type limiter struct {
+	config PolitenessConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState is the rate limiting state of one host
+type hostState struct {
+	policy HostPolicy
+	slots  chan struct{}
+
+	mu           sync.Mutex
+	next         time.Time     // earliest start of the next request
+	backoff      time.Duration // extra interval after throttling responses, decays on success
+	blockedUntil time.Time     // honours Retry-After
+}
+
+// newLimiter creates a limiter, filling in defaults
+func newLimiter(cfg PolitenessConfig) *limiter {
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	return &limiter{config: cfg, hosts: make(map[string]*hostState)}
+}
+
+// host returns the state for host, creating it on first use
+func (l *limiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if h, ok := l.hosts[host]; ok {
+		return h
+	}
+
+	policy := l.config.Default
+	longest := -1
+	for pattern, p := range l.config.Hosts {
+		if len(pattern) > longest && siterules.MatchHost(host, pattern) {
+			policy, longest = p, len(pattern)
+		}
+	}
+	if policy.MaxConcurrent <= 0 {
+		policy.MaxConcurrent = 1
+	}
+
+	h := &hostState{policy: policy, slots: make(chan struct{}, policy.MaxConcurrent)}
+	l.hosts[host] = h
+	return h
+}
+
+// Acquire waits until a request to host may start. The returned function must
+// be called with the outcome of the request to free the slot.
+func (l *limiter) Acquire(ctx context.Context, host string) (func(*FetchResult), error) {
+	h := l.host(host)
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		h.mu.Lock()
+		now := time.Now()
+		start := h.next
+		if h.blockedUntil.After(start) {
+			start = h.blockedUntil
+		}
+		wait := start.Sub(now)
+		if wait <= 0 {
+			h.next = now.Add(h.interval())
+			h.mu.Unlock()
+			break
+		}
+		h.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			<-h.slots
+			return nil, ctx.Err()
+		}
+	}
+
+	return func(res *FetchResult) {
+		l.record(host, h, res)
+		<-h.slots
+	}, nil
+}
+
+// interval is the gap to leave after a request that starts now; h.mu must be held
+func (h *hostState) interval() time.Duration {
+	d := h.policy.MinInterval + h.backoff
+	if h.policy.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(h.policy.Jitter)))
+	}
+	return d
+}
+
+// record adjusts host's pace to the response. res is nil when the request
+// failed without one.
+func (l *limiter) record(host string, h *hostState, res *FetchResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if res == nil || !isThrottled(res.StatusCode) {
+		// Recover gradually, so a store that just throttled us isn't hit at
+		// full speed right away
+		if h.backoff > 0 {
+			h.backoff /= 2
+			if h.backoff < time.Second {
+				h.backoff = 0
+			}
+		}
+		return
+	}
+
+	h.backoff *= 2
+	if h.backoff < time.Second {
+		h.backoff = time.Second
+	}
+	if h.backoff < h.policy.MinInterval {
+		h.backoff = h.policy.MinInterval
+	}
+	if h.backoff > l.config.MaxBackoff {
+		h.backoff = l.config.MaxBackoff
+	}
+
+	pause := h.backoff
+	if after, ok := retryAfter(res.Header, time.Now()); ok {
+		pause = after
+		if pause > l.config.MaxBackoff {
+			pause = l.config.MaxBackoff
+		}
+	}
+	if until := time.Now().Add(pause); until.After(h.blockedUntil) {
+		h.blockedUntil = until
+	}
+
+	log.Warn().
+		Str("host", host).
+		Int("status", res.StatusCode).
+		Dur("pause", pause).
+		Dur("backoff", h.backoff).
+		Msg("Store is throttling us, slowing down")
+}
+
+// isThrottled reports whether status asks the client to slow down
+func isThrottled(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retryAfter parses the Retry-After header, which holds either a number of
+// seconds or an HTTP date
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{"-5", 0, false},
+		{"Sun, 10 Mar 2024 12:01:30 GMT", 90 * time.Second, true},
+		{"Sunday, 10-Mar-24 12:00:10 GMT", 10 * time.Second, true},
+		{"Sun, 10 Mar 2024 11:59:00 GMT", 0, true}, // already passed
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		got, ok := retryAfter(header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	l := newLimiter(PolitenessConfig{MaxBackoff: 10 * time.Second})
+	h := l.host("loja.example")
+
+	// Throttling doubles the backoff up to MaxBackoff; anything else halves it
+	// until it drops below a second
+	steps := []struct {
+		status int // 0 for a request without a response
+		want   time.Duration
+	}{
+		{http.StatusTooManyRequests, time.Second},
+		{http.StatusServiceUnavailable, 2 * time.Second},
+		{http.StatusTooManyRequests, 4 * time.Second},
+		{http.StatusTooManyRequests, 8 * time.Second},
+		{http.StatusTooManyRequests, 10 * time.Second},
+		{http.StatusOK, 5 * time.Second},
+		{http.StatusNotFound, 2500 * time.Millisecond},
+		{0, 1250 * time.Millisecond},
+		{http.StatusOK, 0},
+		{http.StatusOK, 0},
+		{http.StatusTooManyRequests, time.Second},
+	}
+	for i, step := range steps {
+		var res *FetchResult
+		if step.status != 0 {
+			res = &FetchResult{StatusCode: step.status, Header: http.Header{}}
+		}
+		l.record("loja.example", h, res)
+		if h.backoff != step.want {
+			t.Fatalf("step %d (status %d): backoff = %v, want %v", i, step.status, h.backoff, step.want)
+		}
+	}
+}
+
+func TestLimiterRetryAfter(t *testing.T) {
+	l := newLimiter(PolitenessConfig{MaxBackoff: time.Minute})
+	h := l.host("loja.example")
+
+	tests := []struct {
+		retryAfter string
+		pause      time.Duration
+	}{
+		{"30", 30 * time.Second},
+		{"3600", time.Minute}, // capped at MaxBackoff
+	}
+	for _, tt := range tests {
+		h.blockedUntil = time.Time{}
+		start := time.Now()
+		l.record("loja.example", h, &FetchResult{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {tt.retryAfter}}})
+		if got := h.blockedUntil.Sub(start); got < tt.pause || got > tt.pause+time.Second {
+			t.Errorf("Retry-After %s: blocked for %v, want %v", tt.retryAfter, got, tt.pause)
+		}
+	}
+}
+
+func TestLimiterHostPolicy(t *testing.T) {
+	l := newLimiter(PolitenessConfig{
+		Default: HostPolicy{MinInterval: time.Second},
+		Hosts: map[string]HostPolicy{
+			"loja.example":     {MinInterval: 5 * time.Second, MaxConcurrent: 2},
+			"api.loja.example": {MinInterval: 10 * time.Second},
+		},
+	})
+	tests := []struct {
+		host          string
+		minInterval   time.Duration
+		maxConcurrent int
+	}{
+		{"store.example", time.Second, 1},
+		{"loja.example", 5 * time.Second, 2},
+		{"www.loja.example", 5 * time.Second, 2},
+		{"api.loja.example", 10 * time.Second, 1},
+	}
+	for _, tt := range tests {
+		h := l.host(tt.host)
+		if h.policy.MinInterval != tt.minInterval || cap(h.slots) != tt.maxConcurrent {
+			t.Errorf("host(%q) = %v with %d slots, want %v with %d", tt.host, h.policy.MinInterval, cap(h.slots), tt.minInterval, tt.maxConcurrent)
+		}
+	}
+}
+
+func TestLimiterAcquireSpacesRequests(t *testing.T) {
+	l := newLimiter(PolitenessConfig{Default: HostPolicy{MinInterval: 50 * time.Millisecond, Jitter: 20 * time.Millisecond}})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.Acquire(ctx, "loja.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release(&FetchResult{StatusCode: http.StatusOK})
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests took %v, want at least 2 intervals", elapsed)
+	}
+
+	// A cancelled wait gives its slot back
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := l.Acquire(cancelled, "loja.example"); err == nil {
+		t.Error("Acquire with a cancelled context succeeded")
+	}
+	if n := len(l.host("loja.example").slots); n != 0 {
+		t.Errorf("%d slots still taken", n)
+	}
+}
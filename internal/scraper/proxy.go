@@ -0,0 +1,138 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ProxyConfig holds the proxy pool configuration
+type ProxyConfig struct {
+	URLs        []string      // http://, https:// or socks5:// proxies, with optional user:password@
+	BanDuration time.Duration // how long a proxy is rested after a store blocks it; defaults to 30m
+	MaxFailures int           // consecutive connection failures before a proxy is rested; defaults to 3
+}
+
+// ErrNoProxy is returned when every proxy in the pool is down or banned by the store
+var ErrNoProxy = errors.New("no usable proxy")
+
+const (
+	defaultProxyBanDuration = 30 * time.Minute
+	defaultProxyMaxFailures = 3
+)
+
+// ProxyPool hands out proxies round-robin, skipping the ones that are failing
+// or that a store has started blocking
+type ProxyPool struct {
+	config ProxyConfig
+
+	mu      sync.Mutex
+	proxies []*proxy
+	next    int
+}
+
+// proxy is a single proxy and its health
+type proxy struct {
+	url       *url.URL
+	transport *http.Transport
+
+	failures  int                  // consecutive connection failures
+	downUntil time.Time            // rested after MaxFailures failures
+	banned    map[string]time.Time // host -> end of ban
+}
+
+// NewProxyPool parses the configured proxies
+func NewProxyPool(cfg ProxyConfig) (*ProxyPool, error) {
+	if cfg.BanDuration <= 0 {
+		cfg.BanDuration = defaultProxyBanDuration
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = defaultProxyMaxFailures
+	}
+
+	pool := &ProxyPool{config: cfg}
+	for _, raw := range cfg.URLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy %q: unsupported scheme %q", u.Redacted(), u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q: missing host", u.Redacted())
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(u)
+		pool.proxies = append(pool.proxies, &proxy{url: u, transport: transport, banned: make(map[string]time.Time)})
+	}
+	return pool, nil
+}
+
+// Len returns the number of proxies in the pool; it is safe to call on nil
+func (p *ProxyPool) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.proxies)
+}
+
+// pick returns the next proxy usable for host
+func (p *ProxyPool) pick(host string) (*proxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(p.proxies); i++ {
+		px := p.proxies[(p.next+i)%len(p.proxies)]
+		if now.Before(px.downUntil) || now.Before(px.banned[host]) {
+			continue
+		}
+		p.next = (p.next + i + 1) % len(p.proxies)
+		return px, nil
+	}
+	return nil, ErrNoProxy
+}
+
+// report records the outcome of a request made through px. res is nil when
+// no response was received; banned is set when the store refused to serve it.
+func (p *ProxyPool) report(px *proxy, host string, res *FetchResult, err error, banned bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case res == nil && err != nil:
+		px.failures++
+		if px.failures >= p.config.MaxFailures {
+			px.downUntil = time.Now().Add(p.config.BanDuration)
+			px.failures = 0
+			log.Warn().Err(err).Str("proxy", px.url.Redacted()).Dur("for", p.config.BanDuration).Msg("Proxy keeps failing, resting it")
+		}
+	case res != nil && res.StatusCode == http.StatusProxyAuthRequired:
+		px.downUntil = time.Now().Add(p.config.BanDuration)
+		log.Warn().Str("proxy", px.url.Redacted()).Msg("Proxy rejected our credentials, resting it")
+	case banned:
+		px.failures = 0
+		px.banned[host] = time.Now().Add(p.config.BanDuration)
+		log.Warn().Str("proxy", px.url.Redacted()).Str("host", host).Int("status", res.StatusCode).Msg("Proxy blocked by store")
+	default:
+		px.failures = 0
+	}
+}
+
+// isBan reports whether the store refused to serve the page to this client
+func isBan(res *FetchResult) bool {
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+// defaultJobLease is used when ScraperConfig.JobLease is not set
+const defaultJobLease = 10 * time.Minute
+
+// CheckFunc is called by ProcessDue after each successful check
+type CheckFunc func(ctx context.Context, product *models.Product, change *Change)
+
+// workerID names this process's leases: host, pid and a random suffix so
+// that restarts don't inherit the leases of the previous run
+func workerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// ProcessDue checks the products whose scrape jobs are due with Workers
+// goroutines, each leasing one job at a time as it frees up, until none are
+// due or ctx is done. Leasing only when a worker is free means no job waits
+// in this process with a lease nobody renews. Leases are renewed while their
+// check runs, and a check whose lease is taken over is cancelled, so that
+// instances sharing a database check each product once. done, when set, is
+// called after each successful check. It returns the number of products
+// checked.
+func (s *PriceScraper) ProcessDue(ctx context.Context, done CheckFunc) int {
+	var checked atomic.Int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job := s.leaseJob(ctx)
+				if job == nil {
+					return
+				}
+				if s.runJob(ctx, job, done) {
+					checked.Add(1)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return int(checked.Load())
+}
+
+// leaseJob leases the next due scrape job, or returns nil when none is due,
+// ctx is done or the queue can't be read
+func (s *PriceScraper) leaseJob(ctx context.Context) *models.ScrapeJob {
+	if ctx.Err() != nil {
+		return nil
+	}
+	leased, err := s.storage.LeaseJobs(ctx, s.owner, time.Now(), s.config.JobLease, 1)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to lease scrape jobs")
+		}
+		return nil
+	}
+	if len(leased) == 0 {
+		return nil
+	}
+	return leased[0]
+}
+
+// runJob checks the product of a leased job and reports whether it was checked
+func (s *PriceScraper) runJob(ctx context.Context, job *models.ScrapeJob, done CheckFunc) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.renewLease(ctx, job.ProductID, cancel)
+
+	product, err := s.storage.GetProductByID(ctx, job.ProductID)
+	if err != nil {
+		log.Error().Err(err).Str("product_id", job.ProductID.String()).Msg("Failed to load product for scrape job")
+		return false
+	}
+	if product == nil {
+		return false // deleted after the lease; its job went with it
+	}
+	if job.Attempts > 1 {
+		log.Debug().Str("product_id", product.ID.String()).Int("attempt", job.Attempts).Str("last_error", job.LastError).Msg("Retrying scrape job")
+	}
+
+	change, err := s.Check(ctx, product)
+	if err == nil && done != nil {
+		done(ctx, product, change)
+	}
+	return true
+}
+
+// renewLease keeps the job's lease alive until ctx is done, cancelling the
+// check if another worker took the job over or the lease can't be renewed
+// before it expires
+func (s *PriceScraper) renewLease(ctx context.Context, productID uuid.UUID, cancel context.CancelFunc) {
+	ticker := time.NewTicker(s.config.JobLease / 3)
+	defer ticker.Stop()
+	expires := time.Now().Add(s.config.JobLease)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		until := time.Now().Add(s.config.JobLease)
+		err := s.storage.RenewLease(ctx, productID, s.owner, until)
+		switch {
+		case err == nil:
+			expires = until
+		case errors.Is(err, storage.ErrLeaseLost):
+			log.Warn().Str("product_id", productID.String()).Msg("Scrape job taken over by another worker, cancelling check")
+			cancel()
+			return
+		case ctx.Err() == nil:
+			log.Error().Err(err).Str("product_id", productID.String()).Msg("Failed to renew scrape job lease")
+			if time.Until(expires) < s.config.JobLease/2 {
+				cancel() // another worker may lease it before we could renew again
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+// leaseRecorder records the limit of every LeaseJobs call
+type leaseRecorder struct {
+	storage.Storage
+
+	mu     sync.Mutex
+	limits []int
+}
+
+func (r *leaseRecorder) LeaseJobs(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]*models.ScrapeJob, error) {
+	r.mu.Lock()
+	r.limits = append(r.limits, limit)
+	r.mu.Unlock()
+	return r.Storage.LeaseJobs(ctx, owner, now, lease, limit)
+}
+
+func TestProcessDue(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := &leaseRecorder{Storage: db}
+	s := NewScraper(store, ScraperConfig{
+		Workers:       2,
+		CheckInterval: time.Hour,
+		Fetcher:       FetcherFixture,
+		FixtureDir:    filepath.Join("testdata", "stores"),
+	})
+
+	const n = 5
+	due := make(map[uuid.UUID]bool, n)
+	for i := 0; i < n; i++ {
+		product := &models.Product{Name: "Fone XYZ", URL: fmt.Sprintf("https://loja.example/fone-xyz?ref=%d", i), Currency: "BRL"}
+		if err := store.CreateProduct(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+		due[product.ID] = true
+	}
+
+	var mu sync.Mutex
+	checked := make(map[uuid.UUID]int)
+	start := time.Now()
+	got := s.ProcessDue(ctx, func(ctx context.Context, product *models.Product, change *Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		checked[product.ID]++
+	})
+
+	if got != n {
+		t.Errorf("ProcessDue = %d, want %d", got, n)
+	}
+	for id := range due {
+		if checked[id] != 1 {
+			t.Errorf("product %s checked %d times, want once", id, checked[id])
+		}
+		product, err := store.GetProductByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if product.CurrentPrice != 199.90 || product.NextCheckAt.Before(start.Add(time.Hour)) {
+			t.Errorf("product %s: price %v, next check %v", id, product.CurrentPrice, product.NextCheckAt)
+		}
+	}
+	for _, limit := range store.limits {
+		if limit != 1 {
+			t.Errorf("LeaseJobs limit %d, want one job per free worker", limit)
+		}
+	}
+
+	if got := s.ProcessDue(ctx, nil); got != 0 {
+		t.Errorf("ProcessDue with nothing due = %d, want 0", got)
+	}
+}
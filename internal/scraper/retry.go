@@ -0,0 +1,184 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrorKind tells callers whether a failed scrape is worth repeating
+type ErrorKind int
+
+const (
+	// Transient failures (timeouts, 5xx, throttling, dropped connections) may
+	// succeed if the scrape is tried again later
+	Transient ErrorKind = iota
+	// Permanent failures (404, unparseable page, misconfiguration) will fail
+	// the same way until something changes
+	Permanent
+)
+
+// String implements fmt.Stringer
+func (k ErrorKind) String() string {
+	if k == Permanent {
+		return "permanent"
+	}
+	return "transient"
+}
+
+// ErrProductGone is returned when the store says the product page no longer exists
+var ErrProductGone = errors.New("product page no longer exists")
+
+// ScrapeError is returned by ScrapeProduct when a product could not be scraped
+type ScrapeError struct {
+	URL        string
+	Kind       ErrorKind
+	Attempts   int // fetch attempts made, including retries
+	StatusCode int // last HTTP status, 0 when no response was received
+	Err        error
+}
+
+// Error implements error
+func (e *ScrapeError) Error() string {
+	return fmt.Sprintf("scrape %s failed (%s, %d attempts): %v", e.URL, e.Kind, e.Attempts, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ScrapeError) Unwrap() error { return e.Err }
+
+// IsTransient reports whether err is a scrape failure worth retrying later
+func IsTransient(err error) bool {
+	var se *ScrapeError
+	return errors.As(err, &se) && se.Kind == Transient
+}
+
+// IsPermanent reports whether err is a scrape failure that retrying won't fix
+func IsPermanent(err error) bool {
+	var se *ScrapeError
+	return errors.As(err, &se) && se.Kind == Permanent
+}
+
+// classify decides whether err is worth retrying
+func classify(err error) ErrorKind {
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch se.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+			return Transient
+		}
+		if se.StatusCode >= 500 {
+			return Transient
+		}
+		return Permanent
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrPriceNotFound), errors.Is(err, ErrProductGone):
+		return Permanent
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrNoProxy),
+		errors.Is(err, ErrBlocked),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE):
+		return Transient
+	case errors.As(err, &netErr):
+		// DNS failures, dial errors and timeouts
+		return Transient
+	}
+	return Permanent
+}
+
+// RetryPolicy controls how failed fetches are repeated
+type RetryPolicy struct {
+	MaxRetries int           // extra attempts after the first; 0 disables retries
+	Delay      time.Duration // wait before the first retry, doubled for each one after
+	MaxDelay   time.Duration // cap on the wait between attempts
+}
+
+// Default retry timings, used when MaxRetries is set without them
+const (
+	defaultRetryDelay    = 5 * time.Second
+	defaultMaxRetryDelay = 5 * time.Minute
+)
+
+// backoff returns how long to wait before retry number attempt (1-based).
+// Half of the delay is randomized so that workers don't retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Delay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// fetchWithRetry fetches req, retrying transient failures according to the
+// retry policy. Failures are returned as *ScrapeError.
+func (s *PriceScraper) fetchWithRetry(ctx context.Context, fetcher Fetcher, host string, req *FetchRequest) (*FetchResult, error) {
+	policy := s.config.Retry
+	for attempt := 1; ; attempt++ {
+		res, err := s.fetch(ctx, fetcher, host, req)
+		if res != nil {
+			var reason string
+			switch {
+			case err == nil:
+				if reason = s.blockReason(host, res); reason != "" {
+					err = fmt.Errorf("%w: %s", ErrBlocked, reason)
+				}
+			default:
+				if reason = s.blockReason(host, res); reason == "" && res.StatusCode == http.StatusForbidden {
+					reason = "status 403"
+				}
+			}
+			s.blocks.record(host, reason)
+		}
+		if err == nil {
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		scrapeErr := &ScrapeError{URL: req.URL, Kind: classify(err), Attempts: attempt, Err: fmt.Errorf("%s: %w", fetcher.Name(), err)}
+		if res != nil {
+			scrapeErr.StatusCode = res.StatusCode
+			if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone {
+				scrapeErr.Err = fmt.Errorf("%w: %w", ErrProductGone, scrapeErr.Err)
+			}
+		}
+		if scrapeErr.Kind == Permanent || attempt > policy.MaxRetries {
+			return nil, scrapeErr
+		}
+
+		wait := policy.backoff(attempt)
+		log.Debug().
+			Err(err).
+			Str("url", req.URL).
+			Int("attempt", attempt).
+			Dur("wait", wait).
+			Msg("Retrying fetch")
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"timeout", context.DeadlineExceeded, Transient},
+		{"wrapped timeout", fmt.Errorf("direct: %w", context.DeadlineExceeded), Transient},
+		{"500", &StatusError{StatusCode: http.StatusInternalServerError}, Transient},
+		{"502", &StatusError{StatusCode: http.StatusBadGateway}, Transient},
+		{"503", &StatusError{StatusCode: http.StatusServiceUnavailable}, Transient},
+		{"429", &StatusError{StatusCode: http.StatusTooManyRequests}, Transient},
+		{"408", &StatusError{StatusCode: http.StatusRequestTimeout}, Transient},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, Transient},
+		{"connection refused", fmt.Errorf("failed to visit url: %w", syscall.ECONNREFUSED), Transient},
+		{"unexpected EOF", io.ErrUnexpectedEOF, Transient},
+		{"dns failure", &net.DNSError{Err: "no such host", Name: "loja.example"}, Transient},
+		{"404", &StatusError{StatusCode: http.StatusNotFound}, Permanent},
+		{"410", &StatusError{StatusCode: http.StatusGone}, Permanent},
+		{"403", &StatusError{StatusCode: http.StatusForbidden}, Permanent},
+		{"price not found", ErrPriceNotFound, Permanent},
+		{"price label unparseable", fmt.Errorf("%w: %w", ErrPriceNotFound, errors.New("malformed number")), Permanent},
+		{"product removed", fmt.Errorf("%w: %w", ErrProductGone, &StatusError{StatusCode: http.StatusGone}), Permanent},
+		{"unknown", errors.New("fetcher \"x\" is not configured"), Permanent},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.err); got != tt.want {
+			t.Errorf("classify(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{Delay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		attempt int
+		base    time.Duration // the wait is randomized between base/2 and base
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := p.backoff(tt.attempt); got < tt.base/2 || got > tt.base {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.base/2, tt.base)
+			}
+		}
+	}
+	if got := (RetryPolicy{}).backoff(1); got != 0 {
+		t.Errorf("backoff without a delay = %v, want 0", got)
+	}
+}
+
+func TestScrapeProductErrors(t *testing.T) {
+	// big enough not to be mistaken for a block page
+	noPrice := "<html><body><h1>Produto</h1><p>" + strings.Repeat("Descrição do produto. ", 60) + "</p></body></html>"
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			return
+		}
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/flaky":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/no-price":
+			w.Write([]byte(noPrice))
+		}
+	}))
+	defer srv.Close()
+	s := NewScraper(nil, ScraperConfig{Retry: RetryPolicy{MaxRetries: 2, Delay: time.Millisecond}})
+
+	tests := []struct {
+		path     string
+		kind     ErrorKind
+		attempts int
+		status   int
+		is       error
+	}{
+		{"/flaky", Transient, 3, http.StatusInternalServerError, nil},
+		{"/gone", Permanent, 1, http.StatusNotFound, ErrProductGone},
+		{"/no-price", Permanent, 1, http.StatusOK, ErrPriceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			requests.Store(0)
+			_, err := s.ScrapeProduct(context.Background(), &models.Product{URL: srv.URL + tt.path})
+			var se *ScrapeError
+			if !errors.As(err, &se) {
+				t.Fatalf("ScrapeProduct error = %v, want a *ScrapeError", err)
+			}
+			if se.Kind != tt.kind || se.Attempts != tt.attempts || se.StatusCode != tt.status {
+				t.Errorf("ScrapeError = %s after %d attempts with status %d, want %s, %d, %d",
+					se.Kind, se.Attempts, se.StatusCode, tt.kind, tt.attempts, tt.status)
+			}
+			if n := int(requests.Load()); n != tt.attempts {
+				t.Errorf("%d requests, want %d", n, tt.attempts)
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("error %v does not wrap %v", err, tt.is)
+			}
+			if IsTransient(err) != (tt.kind == Transient) || IsPermanent(err) != (tt.kind == Permanent) {
+				t.Errorf("IsTransient/IsPermanent disagree with Kind %s", se.Kind)
+			}
+		})
+	}
+}
+
+func TestScrapeProductConnectionFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := srv.URL
+	srv.Close() // nothing listens there any more
+
+	s := NewScraper(nil, ScraperConfig{})
+	_, err := s.ScrapeProduct(context.Background(), &models.Product{URL: addr + "/p/1"})
+	if !IsTransient(err) {
+		t.Errorf("ScrapeProduct error = %v, want a transient *ScrapeError", err)
+	}
+}
@@ -0,0 +1,126 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/temoto/robotstxt"
+)
+
+// Robots policies
+const (
+	RobotsObey   = "obey"   // skip URLs disallowed by robots.txt
+	RobotsWarn   = "warn"   // scrape them anyway, but log a warning
+	RobotsIgnore = "ignore" // don't fetch robots.txt at all
+)
+
+// RobotsConfig controls robots.txt handling
+type RobotsConfig struct {
+	Policy    string        // obey, warn or ignore; defaults to warn
+	UserAgent string        // product token matched against User-agent lines; defaults to PriceWatcher
+	CacheTTL  time.Duration // how long a host's robots.txt is trusted; defaults to 24h
+}
+
+// ErrDisallowedByRobots is returned when robots.txt forbids fetching a product page
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+const (
+	defaultRobotsAgent    = "PriceWatcher"
+	defaultRobotsCacheTTL = 24 * time.Hour
+	// robotsErrorTTL is used for robots.txt files that could not be fetched,
+	// so an outage doesn't decide the policy for a whole day
+	robotsErrorTTL = 10 * time.Minute
+)
+
+// robotsCache keeps the parsed robots.txt of each host
+type robotsCache struct {
+	config RobotsConfig
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// robotsEntry is a cached robots.txt
+type robotsEntry struct {
+	data    *robotstxt.RobotsData // nil when the file could not be fetched
+	expires time.Time
+}
+
+// newRobotsCache creates a cache, filling in defaults
+func newRobotsCache(cfg RobotsConfig) *robotsCache {
+	if cfg.Policy == "" {
+		cfg.Policy = RobotsWarn
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = defaultRobotsAgent
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultRobotsCacheTTL
+	}
+	return &robotsCache{config: cfg, hosts: make(map[string]*robotsEntry)}
+}
+
+// checkRobots applies the robots policy to u, fetching the host's robots.txt
+// with fetcher when it isn't cached. It returns ErrDisallowedByRobots when the
+// policy is obey and the URL is disallowed.
+func (s *PriceScraper) checkRobots(ctx context.Context, fetcher Fetcher, u *url.URL) error {
+	rc := s.robots
+	if rc.config.Policy == RobotsIgnore || fetcher.Name() == FetcherFixture {
+		return nil
+	}
+
+	data, err := s.robotsFor(ctx, fetcher, u)
+	if err != nil {
+		return err
+	}
+	if data == nil || data.TestAgent(u.EscapedPath(), rc.config.UserAgent) {
+		return nil
+	}
+
+	if rc.config.Policy == RobotsObey {
+		return ErrDisallowedByRobots
+	}
+	log.Warn().Str("url", u.String()).Msg("URL is disallowed by robots.txt, scraping anyway")
+	return nil
+}
+
+// robotsFor returns the robots.txt of u's host, or nil when it is unavailable
+func (s *PriceScraper) robotsFor(ctx context.Context, fetcher Fetcher, u *url.URL) (*robotstxt.RobotsData, error) {
+	rc := s.robots
+	key := u.Scheme + "://" + u.Host
+
+	rc.mu.Lock()
+	entry, ok := rc.hosts[key]
+	rc.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.data, nil
+	}
+
+	robotsURL := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+	res, err := s.fetch(ctx, fetcher, u.Hostname(), &FetchRequest{URL: robotsURL})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	entry = &robotsEntry{expires: time.Now().Add(rc.config.CacheTTL)}
+	switch {
+	case res != nil && res.StatusCode < 500:
+		// 4xx means there are no restrictions; a 5xx would mean "disallow
+		// everything" but is usually temporary, so it is treated as an error
+		if entry.data, err = robotstxt.FromStatusAndBytes(res.StatusCode, res.Body); err != nil {
+			log.Debug().Err(err).Str("url", robotsURL).Msg("Failed to parse robots.txt")
+		}
+	default:
+		log.Debug().Err(err).Str("url", robotsURL).Msg("Failed to fetch robots.txt")
+		entry.expires = time.Now().Add(robotsErrorTTL)
+	}
+
+	rc.mu.Lock()
+	rc.hosts[key] = entry
+	rc.mu.Unlock()
+	return entry.data, nil
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"fmt"
+
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
+)
+
+// RuleExtractor applies the site rule matching the page's host.
+// It runs before the generic extractors so hand-written selectors win.
+type RuleExtractor struct {
+	rules *siterules.Set
+}
+
+// NewRuleExtractor creates an extractor backed by the given rules
+func NewRuleExtractor(rules *siterules.Set) *RuleExtractor {
+	return &RuleExtractor{rules: rules}
+}
+
+// Name implements Extractor
+func (r *RuleExtractor) Name() string { return "rule" }
+
+// Extract implements Extractor
+func (r *RuleExtractor) Extract(page *Page) (*Extraction, error) {
+	rule := r.rules.Match(page.URL.Hostname())
+	if rule == nil {
+		return nil, nil
+	}
+
+	ex := &Extraction{}
+	if name, ok := rule.ProductName.Find(page.Doc); ok {
+		ex.Name = name
+	}
+	opts := page.PriceOptions()
+	if text, ok := rule.Price.Find(page.Doc); ok {
+		amount, err := price.ParseWith(text, opts)
+		if err != nil {
+			ex.PriceErr = fmt.Errorf("rule %q: %w", rule.Name, err)
+		} else {
+			ex.Price, ex.Currency = amount.Value, amount.Currency
+		}
+	}
+	if text, ok := rule.OldPrice.Find(page.Doc); ok {
+		if amount, err := price.ParseWith(text, opts); err == nil {
+			ex.OldPrice = amount.Value
+		}
+	}
+	if text, ok := rule.Shipping.Find(page.Doc); ok {
+		if shipping, ok := parseShipping(text, opts); ok {
+			ex.Shipping = &shipping
+		}
+	}
+	if text, ok := rule.Discount.Find(page.Doc); ok {
+		ex.Discount, _ = parsePercent(text)
+	}
+	if src, ok := rule.Image.Find(page.Doc); ok {
+		ex.ImageURL = page.Resolve(src)
+	}
+	ex.Availability = rule.Availability.State(page.Doc)
+	ex.Offers = ruleOffers(page, &rule.Offers)
+	return ex, nil
+}
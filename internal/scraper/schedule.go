@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/schedule"
+	"github.com/PedroM2626/PriceWatcher/internal/storage"
+)
+
+// scheduleNext releases product's scrape job, due again after the check
+// that started at from and ended with checkErr
+func (s *PriceScraper) scheduleNext(ctx context.Context, product *models.Product, from time.Time, checkErr error) {
+	if ctx.Err() != nil {
+		return // the lease expires and another worker retries the check
+	}
+	var lastError string
+	if checkErr != nil {
+		lastError = checkErr.Error()
+	}
+	next := s.scheduleFor(ctx, product).Next(from)
+	err := s.storage.ReleaseJob(ctx, product.ID, s.owner, next, lastError)
+	if errors.Is(err, storage.ErrLeaseLost) {
+		log.Warn().Str("product_id", product.ID.String()).Dur("lease", s.config.JobLease).Msg("Check outlived its job lease")
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to schedule next check")
+		return
+	}
+	product.NextCheckAt = next
+}
+
+// scheduleFor picks the product's own schedule, then its store's, then the
+// adaptive interval when enabled or else the configured CheckInterval.
+// Invalid stored schedules are skipped.
+func (s *PriceScraper) scheduleFor(ctx context.Context, product *models.Product) *schedule.Schedule {
+	if product.Schedule != "" {
+		sched, err := schedule.Parse(product.Schedule)
+		if err == nil {
+			return sched
+		}
+		log.Warn().Err(err).Str("product_id", product.ID.String()).Msg("Ignoring invalid product schedule")
+	}
+
+	for _, host := range scheduleHosts(product) {
+		site, err := s.storage.GetSiteSchedule(ctx, host)
+		if err != nil {
+			log.Error().Err(err).Str("host", host).Msg("Failed to load site schedule")
+			break
+		}
+		if site == nil {
+			continue
+		}
+		sched, err := schedule.Parse(site.Schedule)
+		if err == nil {
+			return sched
+		}
+		log.Warn().Err(err).Str("host", host).Msg("Ignoring invalid site schedule")
+	}
+
+	if s.config.Adaptive.Enabled {
+		return schedule.Every(s.adaptiveInterval(ctx, product))
+	}
+	return schedule.Every(s.config.CheckInterval)
+}
+
+// scheduleHosts lists the hosts a site schedule may be stored under, most
+// specific first
+func scheduleHosts(product *models.Product) []string {
+	host := product.Website
+	if u, err := url.Parse(product.URL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	host = strings.ToLower(host)
+	if host == "" {
+		return nil
+	}
+	if bare := strings.TrimPrefix(host, "www."); bare != host {
+		return []string{host, bare}
+	}
+	return []string{host}
+}
@@ -2,70 +2,694 @@ package scraper
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"sort"
 	"time"
 
-	"github.com/gocolly/colly/v2"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
 	"github.com/PedroM2626/PriceWatcher/internal/storage"
 )
 
 // ScraperConfig holds configuration for the scraper
 type ScraperConfig struct {
-	UserAgent      string
-	RequestDelay   time.Duration
+	UserAgent      string   // sent when header rotation is off
+	UserAgents     []string // rotated when RotateHeaders is set; empty uses built-in browser profiles
+	RotateHeaders  bool
+	Proxies        *ProxyPool    // direct requests go through these proxies when set
+	RequestDelay   time.Duration // minimum interval between requests to the same store, unless Politeness sets one
 	RequestTimeout time.Duration
 	Workers        int
+	CheckInterval  time.Duration  // how long a product waits between checks
+	JobLease       time.Duration  // how long a check may hold its scrape job before another worker takes it over
+	WorkerID       string         // names this process's job leases; defaults to host, pid and a random suffix
+	Rules          *siterules.Set // per-store selectors, consulted before the generic extractors
+
+	ScraperAPI      ScraperAPIConfig
+	ScraperAPIHosts []string // hosts fetched through ScraperAPI instead of directly (JS-heavy stores)
+
+	Fetcher    string            // default fetcher name; defaults to FetcherDirect
+	Fetchers   map[string]string // host pattern -> fetcher name, overriding the default
+	FixtureDir string            // enables the fixture fetcher, serving pages from this directory
+
+	Politeness PolitenessConfig
+	Retry      RetryPolicy
+	Robots     RobotsConfig
+	Offers     OfferPolicy
+	Adaptive   AdaptivePolicy
+
+	CacheDir string        // keeps downloaded pages on disk when set
+	CacheTTL time.Duration // how long cached pages are kept; zero keeps them forever
 }
 
+// ErrPriceNotFound is returned when no extractor could read a price from a page
+// that isn't marked as out of stock
+var ErrPriceNotFound = errors.New("price not found on page")
+
+// defaultCheckInterval is used when ScraperConfig.CheckInterval is not set
+const defaultCheckInterval = time.Hour
+
 // PriceScraper scrapes product pages periodically
 type PriceScraper struct {
 	storage storage.Storage
 	config  ScraperConfig
+
+	pipeline     *Pipeline
+	fetchers     map[string]Fetcher
+	hostFetchers []hostFetcher // most specific pattern first
+	limiter      *limiter
+	robots       *robotsCache
+	blocks       *blockStats
+	cache        *PageCache // nil when disabled
+
+	owner string // lease owner recorded on the scrape jobs this process holds
 }
 
 // NewScraper creates a new instance of PriceScraper
 func NewScraper(storage storage.Storage, cfg ScraperConfig) *PriceScraper {
-	return &PriceScraper{storage: storage, config: cfg}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = defaultCheckInterval
+	}
+	if cfg.JobLease <= 0 {
+		cfg.JobLease = defaultJobLease
+	}
+	if cfg.WorkerID == "" {
+		cfg.WorkerID = workerID()
+	}
+	pipeline := DefaultPipeline()
+	if cfg.Rules.Len() > 0 {
+		pipeline = RulesPipeline(cfg.Rules)
+	}
+	if cfg.Retry.MaxRetries > 0 && cfg.Retry.Delay <= 0 {
+		cfg.Retry.Delay = defaultRetryDelay
+	}
+	if cfg.Retry.MaxDelay <= 0 {
+		cfg.Retry.MaxDelay = defaultMaxRetryDelay
+	}
+	if cfg.Adaptive.MinInterval <= 0 {
+		cfg.Adaptive.MinInterval = defaultAdaptiveMin
+	}
+	if cfg.Adaptive.MaxInterval <= 0 {
+		cfg.Adaptive.MaxInterval = defaultAdaptiveMax
+	}
+	if cfg.Adaptive.MaxInterval < cfg.Adaptive.MinInterval {
+		cfg.Adaptive.MaxInterval = cfg.Adaptive.MinInterval
+	}
+	if cfg.Adaptive.Window <= 0 {
+		cfg.Adaptive.Window = defaultAdaptiveWindow
+	}
+	if cfg.Politeness.Default.MinInterval <= 0 {
+		cfg.Politeness.Default.MinInterval = cfg.RequestDelay
+	}
+	switch cfg.Offers.Rule {
+	case "", OfferRuleLowestNew, OfferRuleLowestTrusted, OfferRuleBuyBox:
+	default:
+		log.Warn().Str("rule", cfg.Offers.Rule).Msg("Unknown offer rule, keeping page prices")
+		cfg.Offers.Rule = ""
+	}
+	s := &PriceScraper{
+		storage:  storage,
+		config:   cfg,
+		pipeline: pipeline,
+		fetchers: make(map[string]Fetcher),
+		limiter:  newLimiter(cfg.Politeness),
+		robots:   newRobotsCache(cfg.Robots),
+		blocks:   newBlockStats(),
+		owner:    cfg.WorkerID,
+	}
+
+	direct := NewDirectFetcher(cfg.UserAgent, cfg.RequestTimeout)
+	if cfg.RotateHeaders {
+		direct.headers = NewHeaderRotator(cfg.UserAgents)
+	}
+	direct.proxies = cfg.Proxies
+	direct.blockReason = s.blockReason
+	s.RegisterFetcher(direct)
+	if cfg.ScraperAPI.APIKey != "" {
+		api := NewScraperAPIClient(cfg.ScraperAPI)
+		api.pipeline = pipeline
+		s.RegisterFetcher(api)
+	}
+	if cfg.FixtureDir != "" {
+		s.RegisterFetcher(NewFixtureFetcher(cfg.FixtureDir))
+	}
+	if cfg.CacheDir != "" {
+		cache, err := NewPageCache(cfg.CacheDir, cfg.CacheTTL)
+		if err != nil {
+			log.Error().Err(err).Msg("Page cache disabled")
+		}
+		s.cache = cache
+	}
+
+	for _, host := range cfg.ScraperAPIHosts {
+		s.hostFetchers = append(s.hostFetchers, hostFetcher{pattern: host, fetcher: FetcherScraperAPI})
+	}
+	for host, name := range cfg.Fetchers {
+		s.hostFetchers = append(s.hostFetchers, hostFetcher{pattern: host, fetcher: name})
+	}
+	sort.SliceStable(s.hostFetchers, func(i, j int) bool {
+		return len(s.hostFetchers[i].pattern) > len(s.hostFetchers[j].pattern)
+	})
+	return s
 }
 
-// Run starts the scraping process (no-op placeholder loop for now)
-func (s *PriceScraper) Run() error {
-	return nil
+// hostFetcher routes the pages of the stores matching pattern to a fetcher
+type hostFetcher struct {
+	pattern string
+	fetcher string
+}
+
+// RegisterFetcher makes f available under f.Name(), replacing any fetcher
+// registered with the same name. It must be called before Run.
+func (s *PriceScraper) RegisterFetcher(f Fetcher) {
+	s.fetchers[f.Name()] = f
+}
+
+// fetcherFor picks the fetcher for a product: its own setting first, then
+// the per-host configuration, then the default
+func (s *PriceScraper) fetcherFor(product *models.Product, host string) (Fetcher, error) {
+	name := product.Fetcher
+	if name == "" {
+		for _, hf := range s.hostFetchers {
+			if siterules.MatchHost(host, hf.pattern) {
+				name = hf.fetcher
+				break
+			}
+		}
+	}
+	if name == "" {
+		name = s.config.Fetcher
+	}
+	if name == "" {
+		name = FetcherDirect
+	}
+
+	f, ok := s.fetchers[name]
+	if !ok {
+		return nil, fmt.Errorf("fetcher %q is not configured", name)
+	}
+	return f, nil
+}
+
+// Run processes the scrape queue with a pool of Workers goroutines whenever
+// products are due, calling done after each successful check (typically to
+// evaluate the product's alerts). It blocks until ctx is cancelled and all
+// in-flight scrapes have finished.
+func (s *PriceScraper) Run(ctx context.Context, done CheckFunc) error {
+	// Poll often enough that short check intervals and per-product schedules
+	// are honoured, but don't hammer the database for long ones.
+	poll := s.config.CheckInterval
+	if poll > time.Minute {
+		poll = time.Minute
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	log.Info().Int("workers", s.config.Workers).Dur("interval", s.config.CheckInterval).Str("worker_id", s.owner).Msg("Scraper started")
+
+	for {
+		s.ProcessDue(ctx, done)
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Scraper stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check scrapes the tracked product, merges the result into it and persists
+// it, appending the observation to the price history. Failures are recorded
+// on the product instead. Either way the check is logged as a scrape run and
+// the product's next check is scheduled.
+func (s *PriceScraper) Check(ctx context.Context, product *models.Product) (*Change, error) {
+	start := time.Now()
+	// Timeouts are applied by the fetchers, so that time spent waiting for
+	// the host's rate limit doesn't count against the request
+	scraped, err := s.ScrapeProduct(ctx, product)
+	if err != nil {
+		s.handleScrapeError(ctx, product, err)
+		s.addRun(ctx, product, start, err)
+		s.scheduleNext(ctx, product, start, err)
+		return nil, err
+	}
+	change, err := s.Save(ctx, product, scraped)
+	if err == nil {
+		change.Started = start
+		s.addHistory(ctx, product)
+	}
+	s.addRun(ctx, product, start, err)
+	s.scheduleNext(ctx, product, start, err)
+	return change, err
+}
+
+// Save merges scraped into the tracked product and persists it along with
+// the offers found on the page
+func (s *PriceScraper) Save(ctx context.Context, product, scraped *models.Product) (*Change, error) {
+	change := Merge(product, scraped)
+	if err := s.storage.UpdateProduct(ctx, product); err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to update product")
+		return nil, err
+	}
+
+	if change.StockChanged {
+		log.Info().
+			Str("product_id", product.ID.String()).
+			Str("from", string(change.Old.Availability)).
+			Str("to", string(product.Availability)).
+			Msg("Availability changed")
+	}
+	if product.CurrentPrice > 0 && change.PriceChanged {
+		log.Info().
+			Str("product_id", product.ID.String()).
+			Float64("old_price", change.Old.CurrentPrice).
+			Float64("new_price", product.CurrentPrice).
+			Float64("shipping", product.ShippingCost).
+			Float64("list_price", product.ListPrice).
+			Msg("Price updated")
+	}
+	s.saveOffers(ctx, product, scraped.Offers)
+	return change, nil
+}
+
+// addHistory records product's current price and availability
+func (s *PriceScraper) addHistory(ctx context.Context, product *models.Product) {
+	entry := &models.PriceHistory{
+		ProductID:       product.ID,
+		Price:           product.CurrentPrice,
+		Shipping:        product.ShippingCost,
+		FreeShipping:    product.FreeShipping,
+		ListPrice:       product.ListPrice,
+		DiscountPercent: product.DiscountPercent,
+		Availability:    product.Availability,
+	}
+	if err := s.storage.AddPriceHistory(ctx, entry); err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to add price history")
+	}
+}
+
+// addRun logs one check of product, started at start, as a scrape run
+func (s *PriceScraper) addRun(ctx context.Context, product *models.Product, start time.Time, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	run := &models.ScrapeRun{
+		ProductID:  product.ID,
+		Status:     ScrapeStatus(err),
+		StartedAt:  start,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		run.Price = product.CurrentPrice
+		run.Availability = product.Availability
+	}
+	if err := s.storage.AddScrapeRun(ctx, run); err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to record scrape run")
+	}
+}
+
+// saveOffers records the offers of a check when they differ from the last ones
+func (s *PriceScraper) saveOffers(ctx context.Context, product *models.Product, offers []models.Offer) {
+	product.Offers = offers
+	if len(offers) == 0 {
+		return
+	}
+	last, err := s.storage.GetLatestOffers(ctx, product.ID)
+	if err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to load offers")
+		return
+	}
+	if !offersChanged(last, offers) {
+		return
+	}
+	if err := s.storage.AddOffers(ctx, product.ID, offers); err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to add offers")
+	}
+}
+
+// handleScrapeError logs a failed check and records it on the product.
+// Products whose page is gone are marked discontinued; transient failures are
+// simply picked up next interval.
+func (s *PriceScraper) handleScrapeError(ctx context.Context, product *models.Product, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	event := log.Error()
+	if IsTransient(err) || errors.Is(err, ErrDisallowedByRobots) || errors.Is(err, ErrBlocked) {
+		event = log.Warn()
+	}
+	event.Err(err).
+		Str("product_id", product.ID.String()).
+		Str("url", product.URL).
+		Msg("Failed to scrape product")
+
+	status := ScrapeStatus(err)
+	gone := status == models.ScrapeStatusGone && product.Availability != models.AvailabilityDiscontinued
+	if status == product.ScrapeStatus && !gone {
+		return
+	}
+	product.ScrapeStatus = status
+	if gone {
+		product.SetAvailability(models.AvailabilityDiscontinued)
+	}
+	if err := s.storage.UpdateProduct(ctx, product); err != nil {
+		log.Error().Err(err).Str("product_id", product.ID.String()).Msg("Failed to update product")
+		return
+	}
+	if gone {
+		s.addHistory(ctx, product)
+	}
+}
+
+// ScrapeStatus maps the result of ScrapeProduct to a models.ScrapeStatus* value
+func ScrapeStatus(err error) string {
+	switch {
+	case err == nil:
+		return models.ScrapeStatusOK
+	case errors.Is(err, ErrDisallowedByRobots):
+		return models.ScrapeStatusDisallowed
+	case errors.Is(err, ErrProductGone):
+		return models.ScrapeStatusGone
+	case errors.Is(err, ErrBlocked):
+		return models.ScrapeStatusBlocked
+	}
+	return models.ScrapeStatusFailed
 }
 
 // Scrape extracts product information from the given URL
 func (s *PriceScraper) Scrape(ctx context.Context, productURL string) (*models.Product, error) {
-	u, err := url.Parse(productURL)
+	return s.ScrapeProduct(ctx, &models.Product{URL: productURL})
+}
+
+// ScrapeProduct downloads product's page with the fetcher selected for it and
+// extracts the current product information. product itself is not modified.
+// Transient fetch failures are retried; failures are returned as *ScrapeError
+// unless ctx was cancelled.
+func (s *PriceScraper) ScrapeProduct(ctx context.Context, product *models.Product) (*models.Product, error) {
+	u, err := url.Parse(product.URL)
+	if err != nil {
+		return nil, &ScrapeError{URL: product.URL, Kind: Permanent, Err: fmt.Errorf("invalid URL: %w", err)}
+	}
+
+	fetcher, err := s.fetcherFor(product, u.Hostname())
+	if err != nil {
+		return nil, &ScrapeError{URL: product.URL, Kind: Permanent, Err: err}
+	}
+	if err := s.checkRobots(ctx, fetcher, u); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &ScrapeError{URL: product.URL, Kind: Permanent, Err: err}
+	}
+
+	// Only ask for a 304 when we can do something with it: either the cache
+	// has the page to re-extract from, or there is no cache at all
+	req := &FetchRequest{URL: product.URL}
+	cached := s.cachedPage(product.URL)
+	if cached != nil || s.cache == nil {
+		req.Header = conditionalHeader(product)
+	}
+
+	res, err := s.fetchWithRetry(ctx, fetcher, u.Hostname(), req)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().
+		Str("url", product.URL).
+		Str("fetcher", res.Fetcher).
+		Int("status", res.StatusCode).
+		Dur("duration", res.Duration).
+		Msg("Fetched product page")
+
+	notModified := res.StatusCode == http.StatusNotModified
+	body := res.Body
+	if notModified {
+		if cached == nil {
+			// Nothing to extract from, and nothing has changed
+			unchanged := *product
+			return &unchanged, nil
+		}
+		body = cached.Body
+	}
+
+	page, err := NewPage(u, body)
+	if err != nil {
+		return nil, &ScrapeError{URL: product.URL, Kind: Permanent, Attempts: 1, StatusCode: res.StatusCode, Err: err}
+	}
+	if !notModified {
+		if sel := missingExpected(s.config.Rules.Match(u.Hostname()), page); sel != "" {
+			return nil, s.blockedError(res, u.Hostname(), fmt.Sprintf("expected element %q is missing", sel))
+		}
+	}
+
+	scraped, err := extractPage(s.pipeline, page)
+	if !notModified {
+		if errors.Is(err, ErrPriceNotFound) && len(body) < minPageSize {
+			return nil, s.blockedError(res, u.Hostname(), fmt.Sprintf("suspiciously small page (%d bytes)", len(body)))
+		}
+		// Pages that fail extraction are kept too, so they can be re-extracted
+		// once the selectors are fixed
+		if s.cache != nil {
+			if err := s.cache.Put(product.URL, body, res.FetchedAt); err != nil {
+				log.Warn().Err(err).Str("url", product.URL).Msg("Failed to cache product page")
+			}
+		}
+	}
+	if err == nil {
+		err = s.selectPrice(product, scraped)
+	}
+	if err != nil {
+		return nil, &ScrapeError{URL: product.URL, Kind: classify(err), Attempts: 1, StatusCode: res.StatusCode, Err: err}
+	}
+	scraped.ETag, scraped.LastModified = res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+	if res.StatusCode == http.StatusNotModified {
+		// 304 responses may omit the validators; the old ones still apply
+		if scraped.ETag == "" {
+			scraped.ETag = product.ETag
+		}
+		if scraped.LastModified == "" {
+			scraped.LastModified = product.LastModified
+		}
+	}
+	return scraped, nil
+}
+
+// selectPrice settles which of the prices on the page is product's: the offer
+// picked by the offer rule, then the pinned variant's
+func (s *PriceScraper) selectPrice(product, scraped *models.Product) error {
+	pagePrice := scraped.CurrentPrice
+	applyOffers(s.config.Offers, scraped)
+	if err := applyVariant(product, scraped); err != nil {
+		return err
+	}
+	// The advertised discount belongs to the page price; measure another
+	// price against the list price instead
+	if scraped.CurrentPrice != pagePrice && scraped.ListPrice > 0 {
+		scraped.DiscountPercent = discountPercent(scraped.ListPrice, scraped.CurrentPrice)
+		if scraped.DiscountPercent == 0 {
+			scraped.ListPrice = 0
+		}
+	}
+	return nil
+}
+
+// ErrNotCached is returned by ExtractCached when the cache has no copy of a page
+var ErrNotCached = errors.New("page is not cached")
+
+// ExtractCached re-runs extraction on the cached copy of product's page,
+// without any network access. Useful after fixing selectors.
+func (s *PriceScraper) ExtractCached(product *models.Product) (*models.Product, error) {
+	u, err := url.Parse(product.URL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
+	cached := s.cachedPage(product.URL)
+	if cached == nil {
+		return nil, ErrNotCached
+	}
+
+	scraped, err := extractProduct(s.pipeline, u, cached.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.selectPrice(product, scraped); err != nil {
+		return nil, err
+	}
+	scraped.ETag, scraped.LastModified = product.ETag, product.LastModified
+	return scraped, nil
+}
 
-	c := colly.NewCollector(colly.UserAgent(s.config.UserAgent))
-	c.SetRequestTimeout(s.config.RequestTimeout)
+// ReextractAll re-runs extraction on the cached page of every product and
+// saves the results, returning how many products were updated
+func (s *PriceScraper) ReextractAll(ctx context.Context) (int, error) {
+	if s.cache == nil {
+		return 0, errors.New("page cache is not enabled")
+	}
+	products, err := s.storage.ListProducts(ctx, 0, 0)
+	if err != nil {
+		return 0, err
+	}
 
-	product := &models.Product{
-		ID:        uuid.New(),
-		URL:       productURL,
-		Website:   u.Hostname(),
-		Currency:  "BRL",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	updated := 0
+	for _, p := range products {
+		scraped, err := s.ExtractCached(p)
+		switch {
+		case errors.Is(err, ErrNotCached):
+			continue
+		case err != nil:
+			log.Warn().Err(err).Str("product_id", p.ID.String()).Str("url", p.URL).Msg("Failed to extract cached page")
+			continue
+		}
+		// A re-extraction is not a new observation, only a correction of
+		// one, so the history is only touched when something changed
+		change, err := s.Save(ctx, p, scraped)
+		if err != nil {
+			continue
+		}
+		if change.PriceChanged || change.StockChanged {
+			s.addHistory(ctx, p)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// cachedPage returns the cached copy of url, or nil
+func (s *PriceScraper) cachedPage(url string) *CachedPage {
+	if s.cache == nil {
+		return nil
+	}
+	page, ok := s.cache.Get(url)
+	if !ok {
+		return nil
+	}
+	return page
+}
+
+// conditionalHeader builds the If-None-Match/If-Modified-Since headers for a
+// product checked before
+func conditionalHeader(product *models.Product) http.Header {
+	if product.ETag == "" && product.LastModified == "" {
+		return nil
+	}
+	h := make(http.Header)
+	if product.ETag != "" {
+		h.Set("If-None-Match", product.ETag)
+	}
+	if product.LastModified != "" {
+		h.Set("If-Modified-Since", product.LastModified)
+	}
+	return h
+}
+
+// fetch downloads a page, respecting the host's rate limits.
+// Fixtures are local files and are never throttled.
+func (s *PriceScraper) fetch(ctx context.Context, fetcher Fetcher, host string, req *FetchRequest) (*FetchResult, error) {
+	if fetcher.Name() == FetcherFixture {
+		return fetcher.Fetch(ctx, req)
 	}
 
-	// Try to get the title
-	c.OnHTML("title", func(e *colly.HTMLElement) {
-		if product.Name == "" {
-			product.Name = e.Text
+	release, err := s.limiter.Acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	res, err := fetcher.Fetch(ctx, req)
+	release(res)
+	return res, err
+}
+
+// blockedError records a block page noticed after extraction and returns the
+// error describing it
+func (s *PriceScraper) blockedError(res *FetchResult, host, reason string) error {
+	s.blocks.blocked(host, reason)
+	return &ScrapeError{URL: res.URL, Kind: Transient, Attempts: 1, StatusCode: res.StatusCode, Err: fmt.Errorf("%w: %s", ErrBlocked, reason)}
+}
+
+// extractProduct runs the extraction pipeline over a downloaded page
+func extractProduct(pipeline *Pipeline, u *url.URL, body []byte) (*models.Product, error) {
+	page, err := NewPage(u, body)
+	if err != nil {
+		return nil, err
+	}
+	return extractPage(pipeline, page)
+}
+
+// extractPage runs the extraction pipeline over a parsed page
+func extractPage(pipeline *Pipeline, page *Page) (*models.Product, error) {
+	u := page.URL
+	ex := pipeline.Run(page)
+	log.Debug().Str("url", u.String()).Interface("sources", ex.Sources).Msg("Extracted product page")
+	// A missing price is only acceptable when the page says the product can't be bought
+	if ex.Price <= 0 && (ex.Availability == "" || ex.Availability.Purchasable()) {
+		if ex.PriceErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPriceNotFound, ex.PriceErr)
 		}
-	})
+		return nil, ErrPriceNotFound
+	}
+
+	currency := ex.Currency
+	if currency == "" {
+		currency = "BRL"
+	}
 
-	// Best-effort visit (errors are returned)
-	if err := c.Visit(productURL); err != nil {
-		return nil, fmt.Errorf("failed to visit url: %w", err)
+	var shipping float64
+	if ex.Shipping != nil {
+		shipping = *ex.Shipping
+	}
+	// A "was" price at or below the current one is not a discount, just
+	// another price the page happens to show
+	var listPrice, discount float64
+	if ex.OldPrice > ex.Price && ex.Price > 0 {
+		listPrice = ex.OldPrice
+		discount = ex.Discount
+		if discount <= 0 {
+			discount = discountPercent(listPrice, ex.Price)
+		}
 	}
 
+	now := time.Now()
+	product := &models.Product{
+		ID:              uuid.New(),
+		Name:            ex.Name,
+		URL:             u.String(),
+		ImageURL:        ex.ImageURL,
+		CurrentPrice:    ex.Price,
+		Currency:        currency,
+		ShippingCost:    shipping,
+		FreeShipping:    ex.Shipping != nil && shipping == 0,
+		ListPrice:       listPrice,
+		DiscountPercent: discount,
+		Website:         u.Hostname(),
+		PriceSource:     ex.Sources["price"],
+		Variants:        ex.Variants,
+		Offers:          ex.Offers,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	product.SetAvailability(ex.Availability)
 	return product, nil
 }
+
+// contextTransport binds colly's requests to a context, since colly itself
+// has no notion of cancellation
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
@@ -2,82 +2,194 @@ package scraper
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
 	"github.com/PedroM2626/PriceWatcher/internal/models"
 )
 
+// ScraperAPI output formats
+const (
+	ScraperAPIFormatHTML = "html" // the page body as the store served it
+	ScraperAPIFormatJSON = "json" // the ScraperAPIResponse envelope, requested with output_format=json
+)
+
+// ScraperAPIConfig holds configuration for the ScraperAPI client
+type ScraperAPIConfig struct {
+	APIKey  string
+	Render  bool          // have ScraperAPI execute JavaScript before returning the page
+	Timeout time.Duration // rendered pages can take a while; defaults to 60s
+	BaseURL string        // defaults to http://api.scraperapi.com
+	Format  string        // ScraperAPIFormatHTML (default) or ScraperAPIFormatJSON
+}
+
 // ScraperAPIClient handles communication with the ScraperAPI
 // Documentation: https://www.scraperapi.com/documentation/
 type ScraperAPIClient struct {
-	apiKey string
-	client *http.Client
+	apiKey   string
+	baseURL  string
+	render   bool
+	format   string
+	client   *http.Client
+	pipeline *Pipeline
 }
 
 // NewScraperAPIClient creates a new ScraperAPI client
-func NewScraperAPIClient(apiKey string) *ScraperAPIClient {
+func NewScraperAPIClient(cfg ScraperAPIConfig) *ScraperAPIClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://api.scraperapi.com"
+	}
+	if cfg.Format == "" {
+		cfg.Format = ScraperAPIFormatHTML
+	}
 	return &ScraperAPIClient{
-		apiKey: apiKey,
+		apiKey:  cfg.APIKey,
+		baseURL: cfg.BaseURL,
+		render:  cfg.Render,
+		format:  cfg.Format,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: cfg.Timeout,
 		},
+		pipeline: DefaultPipeline(),
 	}
 }
 
 // ScrapeProduct scrapes product information using ScraperAPI
 func (c *ScraperAPIClient) ScrapeProduct(ctx context.Context, productURL string) (*models.Product, error) {
+	u, err := url.Parse(productURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	res, err := c.Fetch(ctx, &FetchRequest{URL: productURL})
+	if err != nil {
+		return nil, err
+	}
+	return extractProduct(c.pipeline, u, res.Body)
+}
+
+// Name implements Fetcher
+func (c *ScraperAPIClient) Name() string { return FetcherScraperAPI }
+
+// Fetch implements Fetcher, downloading the (optionally rendered) page through ScraperAPI.
+// In ScraperAPIFormatJSON mode the page is unwrapped from the ScraperAPIResponse envelope.
+func (c *ScraperAPIClient) Fetch(ctx context.Context, fr *FetchRequest) (*FetchResult, error) {
 	// Build the ScraperAPI URL
-	baseURL := "http://api.scraperapi.com"
 	params := url.Values{}
 	params.Add("api_key", c.apiKey)
-	params.Add("url", productURL)
-	params.Add("render", "true") // Enable JavaScript rendering
+	params.Add("url", fr.URL)
+	if c.render {
+		params.Add("render", "true") // Enable JavaScript rendering
+	}
+	if c.format == ScraperAPIFormatJSON {
+		params.Add("output_format", "json")
+	}
+	if len(fr.Header) > 0 {
+		params.Add("keep_headers", "true") // Forward conditional request headers to the store
+	}
 
-	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+	reqURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", c.redactAPIKey(err))
 	}
 
 	// Set headers to mimic a real browser
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	for k, v := range fr.Header {
+		req.Header[k] = v
+	}
 
 	// Send the request
+	start := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", c.redactAPIKey(err))
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	res := &FetchResult{
+		URL:        fr.URL,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Fetcher:    FetcherScraperAPI,
+		FetchedAt:  start,
+		Duration:   time.Since(start),
+	}
+
+	// ScraperAPI passes the store's status through (404, 410...) and uses 5xx
+	// for its own failures
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("scraperapi request failed with status %d: %s", resp.StatusCode, string(body))
+		return checkStatus(res)
+	}
+
+	if c.format == ScraperAPIFormatJSON {
+		return decodeScraperAPIResponse(res)
+	}
+	return res, nil
+}
+
+// redactAPIKey masks the api key in the request URL that *url.Error quotes,
+// so failed requests can be logged without leaking it
+func (c *ScraperAPIClient) redactAPIKey(err error) error {
+	var ue *url.Error
+	if c.apiKey != "" && errors.As(err, &ue) {
+		ue.URL = strings.ReplaceAll(ue.URL, url.QueryEscape(c.apiKey), "REDACTED")
 	}
+	return err
+}
 
-	// Parse the response
-	// Note: In a real implementation, you would parse the HTML response
-	// to extract product information. This is a simplified example.
-	product := &models.Product{
-		ID:        uuid.New(),
-		URL:       productURL,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// decodeScraperAPIResponse unwraps the HTML from a JSON response. A 200 envelope
+// without a usable page is reported as a bad gateway so retries treat it like
+// ScraperAPI's own 5xx
+func decodeScraperAPIResponse(res *FetchResult) (*FetchResult, error) {
+	var r ScraperAPIResponse
+	if err := json.Unmarshal(res.Body, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode scraperapi response: %v: %w", err, badGateway(res))
+	}
+	if r.Request.URL != "" {
+		res.URL = r.Request.URL
+	}
+	if r.Request.StatusCode != 0 {
+		res.StatusCode = r.Request.StatusCode
+	}
+	res.Body = []byte(r.HTML)
+	if r.Request.ContentType != "" {
+		res.Header.Set("Content-Type", r.Request.ContentType)
 	}
 
-	// TODO: Parse the HTML response to extract product details
-	// This would involve using goquery or similar to parse the HTML
-	// and extract the product name, price, etc.
+	if res.StatusCode >= 400 {
+		return checkStatus(res)
+	}
+	if !r.Request.Success {
+		return nil, fmt.Errorf("scraperapi could not fetch %s: status %q: %w", res.URL, r.Status, badGateway(res))
+	}
+	if r.HTML == "" {
+		return nil, fmt.Errorf("scraperapi response for %s has no html: %w", res.URL, badGateway(res))
+	}
+	return res, nil
+}
 
-	return product, nil
+// badGateway blames ScraperAPI for a response it could not turn into a page
+func badGateway(res *FetchResult) *StatusError {
+	return &StatusError{StatusCode: http.StatusBadGateway, Result: res}
 }
 
 // ScraperAPIResponse represents the response from ScraperAPI
@@ -0,0 +1,319 @@
+package scraper
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/PedroM2626/PriceWatcher/internal/price"
+)
+
+// JSONLDExtractor reads schema.org Product/Offer data from
+// <script type="application/ld+json"> blocks
+type JSONLDExtractor struct{}
+
+// Name implements Extractor
+func (j *JSONLDExtractor) Name() string { return "jsonld" }
+
+// Extract implements Extractor
+func (j *JSONLDExtractor) Extract(page *Page) (*Extraction, error) {
+	var products []map[string]any
+	page.Doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var data any
+		// Malformed blocks are common; skip them rather than failing the page
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return
+		}
+		for _, node := range ldNodes(data) {
+			if ldHasType(node, "Product", "ProductGroup") {
+				products = append(products, node)
+			}
+		}
+	})
+	if len(products) == 0 {
+		return nil, nil
+	}
+
+	ex := &Extraction{}
+	for _, p := range products {
+		found := &Extraction{
+			Name:     cleanText(ldString(p["name"])),
+			ImageURL: page.Resolve(ldImage(p["image"])),
+		}
+		offers := ldOffers(p["offers"])
+		if offer := bestOffer(offers); offer != nil {
+			found.Price = offer.price
+			found.Currency = offer.currency
+			found.Availability = offer.stock
+			found.Shipping = offer.shipping
+			found.OldPrice = offer.listPrice
+		}
+		found.Offers = jsonLDOffers(offers)
+		found.Variants = ldVariants(page, p)
+		if found.Price <= 0 {
+			// Product groups often only price their variants
+			if v := defaultVariant(found.Variants); v != nil {
+				found.Price = v.Price
+				found.Currency = v.Currency
+				found.Availability = v.Availability
+			}
+		}
+		ex.merge(found, j.Name())
+	}
+	return ex, nil
+}
+
+// ldOffer is the subset of a schema.org Offer we care about
+type ldOffer struct {
+	price     float64
+	listPrice float64 // struck-through price from a ListPrice priceSpecification
+	currency  string
+	stock     models.Availability // "" when the offer doesn't say
+	sku       string              // set when the offer is for one variant of the product
+	name      string
+	url       string
+	seller    string // set on marketplace pages listing several sellers
+	condition string
+	shipping  *float64 // nil when the offer has no shippingDetails
+}
+
+// ldOffers flattens Offer, AggregateOffer and offers[] values into a list of offers
+func ldOffers(v any) []ldOffer {
+	var out []ldOffer
+	for _, node := range ldNodes(v) {
+		currency := ldString(node["priceCurrency"])
+		stock := ldAvailability(ldString(node["availability"]))
+
+		if ldHasType(node, "AggregateOffer") {
+			nested := ldOffers(node["offers"])
+			for i := range nested {
+				if nested[i].currency == "" {
+					nested[i].currency = currency
+				}
+			}
+			// Individual offers say more than the aggregate's lowPrice
+			if len(nested) > 0 {
+				out = append(out, nested...)
+			} else if low, ok := ldPrice(node["lowPrice"]); ok {
+				out = append(out, ldOffer{price: low, currency: currency, stock: stock})
+			}
+			continue
+		}
+
+		value, ok := ldPrice(node["price"])
+		var listPrice float64
+		for _, spec := range ldNodes(node["priceSpecification"]) {
+			if ldIsListPrice(spec) {
+				listPrice, _ = ldPrice(spec["price"])
+				continue
+			}
+			// Some stores only fill in priceSpecification
+			if !ok {
+				if value, ok = ldPrice(spec["price"]); ok && currency == "" {
+					currency = ldString(spec["priceCurrency"])
+				}
+			}
+		}
+		if ok {
+			out = append(out, ldOffer{
+				price:     value,
+				listPrice: listPrice,
+				currency:  currency,
+				stock:     stock,
+				sku:       ldString(node["sku"]),
+				name:      cleanText(ldString(node["name"])),
+				url:       ldString(node["url"]),
+				seller:    ldSeller(node["seller"]),
+				condition: offerCondition(ldString(node["itemCondition"])),
+				shipping:  ldShipping(node["shippingDetails"]),
+			})
+		}
+	}
+	return out
+}
+
+// bestOffer picks the cheapest offer that isn't known to be out of stock,
+// falling back to the cheapest offer overall
+func bestOffer(offers []ldOffer) *ldOffer {
+	var best, bestAny *ldOffer
+	for i := range offers {
+		o := &offers[i]
+		if bestAny == nil || o.price < bestAny.price {
+			bestAny = o
+		}
+		if o.stock != "" && !o.stock.Purchasable() {
+			continue
+		}
+		if best == nil || o.price < best.price {
+			best = o
+		}
+	}
+	if best == nil {
+		return bestAny
+	}
+	return best
+}
+
+// ldIsListPrice reports whether a priceSpecification holds the "was" price
+// rather than the price the product sells for
+func ldIsListPrice(spec map[string]any) bool {
+	t := strings.ToLower(ldString(spec["priceType"]))
+	return strings.HasSuffix(t, "listprice") || strings.HasSuffix(t, "strikethroughprice") || strings.HasSuffix(t, "msrp")
+}
+
+// ldNodes flattens a JSON-LD value (object, array or @graph) into its objects
+func ldNodes(v any) []map[string]any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := []map[string]any{t}
+		if graph, ok := t["@graph"]; ok {
+			out = append(out, ldNodes(graph)...)
+		}
+		return out
+	case []any:
+		var out []map[string]any
+		for _, item := range t {
+			out = append(out, ldNodes(item)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// ldHasType reports whether node's @type is one of types
+func ldHasType(node map[string]any, types ...string) bool {
+	var declared []string
+	switch t := node["@type"].(type) {
+	case string:
+		declared = []string{t}
+	case []any:
+		for _, v := range t {
+			declared = append(declared, ldString(v))
+		}
+	}
+	for _, d := range declared {
+		d = d[strings.LastIndexAny(d, "/:")+1:] // accept "schema:Product" and full URIs
+		for _, want := range types {
+			if strings.EqualFold(d, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ldString returns v as a string, taking the first element of arrays
+func ldString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []any:
+		if len(t) > 0 {
+			return ldString(t[0])
+		}
+	case map[string]any:
+		if name, ok := t["name"]; ok {
+			return ldString(name)
+		}
+	}
+	return ""
+}
+
+// ldImage returns the first image URL from a string, array or ImageObject
+func ldImage(v any) string {
+	switch t := v.(type) {
+	case map[string]any:
+		if u := ldString(t["url"]); u != "" {
+			return u
+		}
+		return ldString(t["contentUrl"])
+	case []any:
+		for _, item := range t {
+			if u := ldImage(item); u != "" {
+				return u
+			}
+		}
+		return ""
+	}
+	return ldString(v)
+}
+
+// ldPrice reads a schema.org price, which may be a number or a string
+func ldPrice(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, t > 0
+	case string:
+		// schema.org mandates a dot decimal separator, but not every store
+		// complies; the parser still copes with "1.299,90"
+		amount, err := price.ParseWith(t, price.Options{Locale: price.Dot})
+		return amount.Value, err == nil
+	}
+	return 0, false
+}
+
+// ldAvailability maps a schema.org ItemAvailability value to an availability
+// state, "" when it isn't one we know
+func ldAvailability(v string) models.Availability {
+	if v == "" {
+		return ""
+	}
+	switch strings.ToLower(v[strings.LastIndexAny(v, "/:")+1:]) {
+	case "instock", "onlineonly", "instoreonly", "limitedavailability", "backorder":
+		return models.AvailabilityInStock
+	case "preorder", "presale":
+		return models.AvailabilityPreorder
+	case "outofstock", "soldout":
+		return models.AvailabilityOutOfStock
+	case "discontinued":
+		return models.AvailabilityDiscontinued
+	}
+	return ""
+}
+
+// MicrodataExtractor reads schema.org Product data from itemprop attributes
+type MicrodataExtractor struct{}
+
+// Name implements Extractor
+func (m *MicrodataExtractor) Name() string { return "microdata" }
+
+// Extract implements Extractor
+func (m *MicrodataExtractor) Extract(page *Page) (*Extraction, error) {
+	scope := page.Doc.Find(`[itemscope][itemtype*="schema.org/Product"]`).First()
+	if scope.Length() == 0 {
+		return nil, nil
+	}
+
+	ex := &Extraction{
+		Name:     cleanText(itemprop(scope, "name")),
+		Currency: itemprop(scope, "priceCurrency"),
+		ImageURL: page.Resolve(itemprop(scope, "image")),
+	}
+	if value, ok := ldPrice(itemprop(scope, "price")); ok {
+		ex.Price = value
+	} else if value, ok := ldPrice(itemprop(scope, "lowPrice")); ok {
+		ex.Price = value
+	}
+	ex.Availability = ldAvailability(itemprop(scope, "availability"))
+	ex.Variants = microdataVariants(page, scope)
+	return ex, nil
+}
+
+// itemprop reads the value of the first matching itemprop inside scope,
+// following the microdata rules for which attribute carries the value
+func itemprop(scope *goquery.Selection, name string) string {
+	s := scope.Find(`[itemprop="` + name + `"]`).First()
+	if s.Length() == 0 {
+		return ""
+	}
+	for _, attr := range []string{"content", "href", "src", "value"} {
+		if v, ok := s.Attr(attr); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return strings.TrimSpace(s.Text())
+}
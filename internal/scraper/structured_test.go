@@ -0,0 +1,111 @@
+package scraper
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+func TestJSONLDExtractor(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		url          string
+		name         string
+		price        float64
+		currency     string
+		image        string
+		availability models.Availability
+	}{
+		{
+			fixture:      "jsonld_offer.html",
+			url:          "https://loja.example/fone-xyz",
+			name:         "Fone Bluetooth XYZ",
+			price:        199.90,
+			currency:     "BRL",
+			image:        "https://loja.example/img/fone-xyz.jpg",
+			availability: models.AvailabilityInStock,
+		},
+		{
+			// The cheapest offer is sold out, so the next one sets the price
+			fixture:      "jsonld_aggregate.html",
+			url:          "https://market.example/k2",
+			name:         "Mechanical Keyboard K2",
+			price:        89.50,
+			currency:     "USD",
+			image:        "https://cdn.example.com/k2.jpg",
+			availability: models.AvailabilityInStock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, _ := url.Parse(tt.url)
+			page, err := NewPage(u, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := (&JSONLDExtractor{}).Extract(page)
+			if err != nil || got == nil {
+				t.Fatalf("Extract = %v, %v", got, err)
+			}
+			if got.Name != tt.name {
+				t.Errorf("Name = %q, want %q", got.Name, tt.name)
+			}
+			if got.Price != tt.price || got.Currency != tt.currency {
+				t.Errorf("Price = %v %q, want %v %q", got.Price, got.Currency, tt.price, tt.currency)
+			}
+			if got.ImageURL != tt.image {
+				t.Errorf("ImageURL = %q, want %q", got.ImageURL, tt.image)
+			}
+			if got.Availability != tt.availability {
+				t.Errorf("Availability = %q, want %q", got.Availability, tt.availability)
+			}
+		})
+	}
+}
+
+func TestJSONLDExtractorWithoutProduct(t *testing.T) {
+	page, err := NewPage(nil, []byte(`<html><head><script type="application/ld+json">{"@type": "Organization", "name": "Loja"}</script></head></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := (&JSONLDExtractor{}).Extract(page); got != nil || err != nil {
+		t.Errorf("Extract = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestMicrodataExtractor(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "microdata.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://loja.example/cafeteira")
+	page, err := NewPage(u, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := (&MicrodataExtractor{}).Extract(page)
+	if err != nil || got == nil {
+		t.Fatalf("Extract = %v, %v", got, err)
+	}
+	if got.Name != "Cafeteira Expresso 15 bar" {
+		t.Errorf("Name = %q", got.Name)
+	}
+	if got.Price != 549 || got.Currency != "BRL" {
+		t.Errorf("Price = %v %q, want 549 BRL", got.Price, got.Currency)
+	}
+	if got.ImageURL != "https://loja.example/fotos/cafeteira.jpg" {
+		t.Errorf("ImageURL = %q", got.ImageURL)
+	}
+	if got.Availability != models.AvailabilityPreorder {
+		t.Errorf("Availability = %q, want %q", got.Availability, models.AvailabilityPreorder)
+	}
+}
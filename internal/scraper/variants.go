@@ -0,0 +1,183 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+// ErrVariantNotFound is returned when the page no longer lists the variant a
+// product is pinned to. Recording the page's headline price instead would
+// silently track a different item.
+var ErrVariantNotFound = errors.New("pinned variant not found on page")
+
+// ldVariantProperties are the schema.org Product properties that tell
+// variants apart
+var ldVariantProperties = []string{"color", "size", "material", "pattern", "model", "suggestedAge", "suggestedGender"}
+
+// ldVariants lists the variants of a ProductGroup (hasVariant), or of a
+// Product whose offers each carry their own SKU
+func ldVariants(page *Page, p map[string]any) []models.Variant {
+	var out []models.Variant
+	for _, node := range ldNodes(p["hasVariant"]) {
+		v := models.Variant{
+			SKU:        ldString(node["sku"]),
+			Name:       cleanText(ldString(node["name"])),
+			Attributes: ldVariantAttributes(node),
+			URL:        page.Resolve(ldString(node["url"])),
+		}
+		if offer := bestOffer(ldOffers(node["offers"])); offer != nil {
+			v.Price = offer.price
+			v.Currency = offer.currency
+			v.Availability = offer.stock
+			if v.URL == "" {
+				v.URL = page.Resolve(offer.url)
+			}
+		}
+		out = append(out, v)
+	}
+	if len(out) > 0 {
+		return out
+	}
+
+	offers := ldOffers(p["offers"])
+	for _, offer := range offers {
+		if offer.sku == "" {
+			continue
+		}
+		out = append(out, models.Variant{
+			SKU:          offer.sku,
+			Name:         offer.name,
+			Price:        offer.price,
+			Currency:     offer.currency,
+			Availability: offer.stock,
+			URL:          page.Resolve(offer.url),
+		})
+	}
+	// A single SKU'd offer is just the product itself
+	if len(out) < 2 {
+		return nil
+	}
+	return out
+}
+
+// ldVariantAttributes collects the distinguishing properties of a variant,
+// keyed by lowercase property name
+func ldVariantAttributes(node map[string]any) map[string]string {
+	attrs := make(map[string]string)
+	for _, prop := range ldVariantProperties {
+		if v := cleanText(ldString(node[prop])); v != "" {
+			attrs[strings.ToLower(prop)] = v
+		}
+	}
+	for _, pv := range ldNodes(node["additionalProperty"]) {
+		name := strings.ToLower(cleanText(ldString(pv["name"])))
+		value := cleanText(ldString(pv["value"]))
+		if name != "" && value != "" {
+			attrs[name] = value
+		}
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// microdataVariants lists the offers inside a microdata Product scope that
+// carry their own SKU, when there is more than one
+func microdataVariants(page *Page, scope *goquery.Selection) []models.Variant {
+	var out []models.Variant
+	scope.Find(`[itemprop="offers"][itemscope]`).Each(func(_ int, offer *goquery.Selection) {
+		sku := itemprop(offer, "sku")
+		if sku == "" {
+			return
+		}
+		v := models.Variant{
+			SKU:          sku,
+			Name:         cleanText(itemprop(offer, "name")),
+			Currency:     itemprop(offer, "priceCurrency"),
+			Availability: ldAvailability(itemprop(offer, "availability")),
+			URL:          page.Resolve(itemprop(offer, "url")),
+		}
+		if value, ok := ldPrice(itemprop(offer, "price")); ok {
+			v.Price = value
+		}
+		out = append(out, v)
+	})
+	if len(out) < 2 {
+		return nil
+	}
+	return out
+}
+
+// defaultVariant picks the variant whose price stands for the whole product
+// when the page has no price of its own: the cheapest one in stock, falling
+// back to the cheapest one overall
+func defaultVariant(variants []models.Variant) *models.Variant {
+	priced := make([]*models.Variant, 0, len(variants))
+	for i := range variants {
+		if variants[i].Price > 0 {
+			priced = append(priced, &variants[i])
+		}
+	}
+	if len(priced) == 0 {
+		return nil
+	}
+	sort.SliceStable(priced, func(i, j int) bool { return priced[i].Price < priced[j].Price })
+	for _, v := range priced {
+		if v.Availability == "" || v.Availability.Purchasable() {
+			return v
+		}
+	}
+	return priced[0]
+}
+
+// applyVariant replaces the page-level price and availability of scraped with
+// those of the variant product is pinned to
+func applyVariant(product, scraped *models.Product) error {
+	if !product.PinsVariant() {
+		return nil
+	}
+	for i := range scraped.Variants {
+		v := &scraped.Variants[i]
+		if !v.Matches(product.VariantSKU, product.VariantAttributes) {
+			continue
+		}
+		// Variants listed without a state are taken to be on sale
+		availability := v.Availability
+		if availability == "" {
+			availability = models.AvailabilityInStock
+		}
+		if v.Price <= 0 && availability.Purchasable() {
+			return fmt.Errorf("%w: variant %s has no price", ErrPriceNotFound, variantLabel(product))
+		}
+		scraped.CurrentPrice = v.Price
+		if v.Currency != "" {
+			scraped.Currency = v.Currency
+		}
+		scraped.SetAvailability(availability)
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrVariantNotFound, variantLabel(product))
+}
+
+// variantLabel describes the variant product is pinned to, for error messages
+func variantLabel(product *models.Product) string {
+	if product.VariantSKU != "" {
+		return "sku " + product.VariantSKU
+	}
+	keys := make([]string, 0, len(product.VariantAttributes))
+	for k := range product.VariantAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + product.VariantAttributes[k]
+	}
+	return strings.Join(parts, ", ")
+}
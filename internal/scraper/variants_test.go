@@ -0,0 +1,102 @@
+package scraper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+func TestApplyVariant(t *testing.T) {
+	variants := []models.Variant{
+		{SKU: "CEL-128-PT", Attributes: map[string]string{"color": "Preto", "storage": "128 GB"}, Price: 2499, Currency: "BRL", Availability: models.AvailabilityInStock},
+		{SKU: "CEL-256-PT", Attributes: map[string]string{"color": "Preto", "storage": "256 GB"}, Price: 2899, Currency: "BRL", Availability: models.AvailabilityOutOfStock},
+		{SKU: "CEL-256-AZ", Attributes: map[string]string{"color": "Azul", "storage": "256 GB"}, Price: 2999},
+		{SKU: "CEL-512-AZ", Attributes: map[string]string{"color": "Azul", "storage": "512 GB"}, Availability: models.AvailabilityOutOfStock},
+		{SKU: "CEL-512-PT", Attributes: map[string]string{"color": "Preto", "storage": "512 GB"}},
+		{SKU: "CEL-1TB-AZ", Attributes: map[string]string{"color": "Azul", "storage": "1 TB"}, Price: 3999, Availability: models.AvailabilityPreorder},
+	}
+
+	tests := []struct {
+		name         string
+		sku          string
+		attrs        map[string]string
+		price        float64
+		currency     string
+		availability models.Availability
+		wantErr      error
+	}{
+		{name: "not pinned", price: 2499, currency: "USD", availability: models.AvailabilityInStock},
+		{name: "sku", sku: "CEL-256-PT", price: 2899, currency: "BRL", availability: models.AvailabilityOutOfStock},
+		{name: "sku ignores case and spaces", sku: " cel-128-pt ", price: 2499, currency: "BRL", availability: models.AvailabilityInStock},
+		{name: "attributes", attrs: map[string]string{"color": "preto", "storage": "256gb"}, price: 2899, currency: "BRL", availability: models.AvailabilityOutOfStock},
+		{name: "attributes keep page currency", attrs: map[string]string{"Color": "AZUL", "Storage": "256 GB"}, price: 2999, currency: "USD", availability: models.AvailabilityInStock},
+		{name: "first match on partial attributes", attrs: map[string]string{"storage": "256 GB"}, price: 2899, currency: "BRL", availability: models.AvailabilityOutOfStock},
+		{name: "preorder", sku: "CEL-1TB-AZ", price: 3999, currency: "USD", availability: models.AvailabilityPreorder},
+		{name: "out of stock without price", sku: "CEL-512-AZ", price: 0, currency: "USD", availability: models.AvailabilityOutOfStock},
+		{name: "unknown sku", sku: "CEL-1TB-PT", wantErr: ErrVariantNotFound},
+		{name: "unknown attributes", attrs: map[string]string{"color": "Verde"}, wantErr: ErrVariantNotFound},
+		{name: "in stock without price", sku: "CEL-512-PT", wantErr: ErrPriceNotFound},
+	}
+
+	for _, tt := range tests {
+		product := &models.Product{VariantSKU: tt.sku, VariantAttributes: tt.attrs}
+		scraped := &models.Product{CurrentPrice: 2499, Currency: "USD", Variants: variants}
+		scraped.SetAvailability(models.AvailabilityInStock)
+
+		err := applyVariant(product, scraped)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if scraped.CurrentPrice != tt.price || scraped.Currency != tt.currency || scraped.Availability != tt.availability {
+			t.Errorf("%s: got %v %s %s, want %v %s %s",
+				tt.name, scraped.CurrentPrice, scraped.Currency, scraped.Availability, tt.price, tt.currency, tt.availability)
+		}
+		if scraped.IsAvailable != tt.availability.Purchasable() {
+			t.Errorf("%s: IsAvailable = %v out of step with %s", tt.name, scraped.IsAvailable, scraped.Availability)
+		}
+	}
+}
+
+func TestDefaultVariant(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []models.Variant
+		want     string // SKU, "" for none
+	}{
+		{name: "none"},
+		{name: "no prices", variants: []models.Variant{{SKU: "A"}, {SKU: "B"}}},
+		{
+			name:     "cheapest in stock",
+			variants: []models.Variant{{SKU: "A", Price: 50, Availability: models.AvailabilityOutOfStock}, {SKU: "B", Price: 80, Availability: models.AvailabilityInStock}, {SKU: "C", Price: 70}},
+			want:     "C",
+		},
+		{
+			name:     "preorders can be bought",
+			variants: []models.Variant{{SKU: "A", Price: 50, Availability: models.AvailabilityDiscontinued}, {SKU: "B", Price: 60, Availability: models.AvailabilityPreorder}},
+			want:     "B",
+		},
+		{
+			name:     "cheapest when all are out of stock",
+			variants: []models.Variant{{SKU: "A", Price: 90, Availability: models.AvailabilityOutOfStock}, {SKU: "B", Price: 60, Availability: models.AvailabilityOutOfStock}},
+			want:     "B",
+		},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if v := defaultVariant(tt.variants); v != nil {
+			got = v.SKU
+		}
+		if got != tt.want {
+			t.Errorf("%s: defaultVariant = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+// Package siterules loads per-store extraction rules from YAML files, so new
+// stores can be supported without recompiling.
+//
+// A rule file holds one rule or a list of rules:
+//
+//	name: kabum
+//	hosts: ["kabum.com.br"]
+//	name_selector: "h1"
+//	price:
+//	  css: ".finalPrice"
+//	old_price:
+//	  xpath: "//span[contains(@class,'oldPrice')]"
+//	  regex: 'R\$\s*([\d.,]+)'
+//	discount: ".discount-badge"
+//	image:
+//	  css: "#carouselDetails img"
+//	  attr: "src"
+//	availability:
+//	  css: ".buttonsArea"
+//	  out_of_stock: ["esgotado", "avise-me"]
+//	  preorder: ["pré-venda"]
+//	expect: ["h1", ".finalPrice"]
+//	offers:
+//	  item: ".sellers-list li"
+//	  seller: ".seller-name"
+//	  price: ".seller-price"
+//	  buy_box: ".selected"
+//	block_signatures: ["radware bot manager"]
+package siterules
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"gopkg.in/yaml.v3"
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+// Rule describes how to read product fields on the pages of one store
+type Rule struct {
+	Name  string   `yaml:"name"`
+	Hosts []string `yaml:"hosts"` // "store.com" (also matches subdomains) or glob patterns like "*.store.com.br"
+
+	ProductName  Selector     `yaml:"name_selector"`
+	Price        Selector     `yaml:"price"`
+	OldPrice     Selector     `yaml:"old_price"`
+	Discount     Selector     `yaml:"discount"` // advertised discount, e.g. "25% OFF"
+	Image        Selector     `yaml:"image"`
+	Shipping     Selector     `yaml:"shipping"` // "frete grátis" reads as free shipping
+	Availability Availability `yaml:"availability"`
+	Offers       Offers       `yaml:"offers"`
+
+	// Block page detection: a page missing any Expect element, or containing
+	// any BlockSignatures phrase, is treated as an anti-bot page
+	Expect          []Selector `yaml:"expect"`
+	BlockSignatures []string   `yaml:"block_signatures"`
+
+	file string
+}
+
+// Selector locates a value on the page with either CSS or XPath.
+// A plain string in YAML is shorthand for a CSS selector.
+type Selector struct {
+	CSS   string `yaml:"css"`
+	XPath string `yaml:"xpath"`
+	Attr  string `yaml:"attr"`  // read this attribute instead of the element text
+	Regex string `yaml:"regex"` // keep only the first capture group (or the whole match)
+
+	xpath *xpath.Expr
+	regex *regexp.Regexp
+}
+
+// Offers reads the seller list of marketplace pages. Item matches one element
+// per offer; the other selectors are evaluated inside it.
+type Offers struct {
+	Item        string   `yaml:"item"`
+	Seller      Selector `yaml:"seller"`
+	Price       Selector `yaml:"price"`
+	Shipping    Selector `yaml:"shipping"`
+	Condition   Selector `yaml:"condition"`
+	Fulfillment Selector `yaml:"fulfillment"`
+	BuyBox      string   `yaml:"buy_box"` // CSS selector matching the buy-box offer element or an element inside it
+}
+
+// Availability decides the stock state of a product from a selector's text.
+// When no list is set, the presence of the element means "in stock".
+type Availability struct {
+	Selector     `yaml:",inline"`
+	InStock      []string `yaml:"in_stock"`
+	OutOfStock   []string `yaml:"out_of_stock"`
+	Preorder     []string `yaml:"preorder"`
+	Discontinued []string `yaml:"discontinued"`
+}
+
+// UnmarshalYAML accepts either a CSS selector string or a full mapping
+func (s *Selector) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.CSS = node.Value
+		return nil
+	}
+	type plain Selector
+	return node.Decode((*plain)(s))
+}
+
+// UnmarshalYAML decodes the phrase lists next to the inline selector; without
+// it, the selector's own UnmarshalYAML would be promoted and drop them
+func (a *Availability) UnmarshalYAML(node *yaml.Node) error {
+	if err := a.Selector.UnmarshalYAML(node); err != nil {
+		return err
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var lists struct {
+		InStock      []string `yaml:"in_stock"`
+		OutOfStock   []string `yaml:"out_of_stock"`
+		Preorder     []string `yaml:"preorder"`
+		Discontinued []string `yaml:"discontinued"`
+	}
+	if err := node.Decode(&lists); err != nil {
+		return err
+	}
+	a.InStock, a.OutOfStock, a.Preorder, a.Discontinued = lists.InStock, lists.OutOfStock, lists.Preorder, lists.Discontinued
+	return nil
+}
+
+// IsZero reports whether the selector is unset
+func (s *Selector) IsZero() bool {
+	return s.CSS == "" && s.XPath == ""
+}
+
+// compile validates and pre-compiles the XPath expression and regex
+func (s *Selector) compile() error {
+	if s.CSS != "" && s.XPath != "" {
+		return fmt.Errorf("selector has both css and xpath")
+	}
+	if s.XPath != "" {
+		expr, err := xpath.Compile(s.XPath)
+		if err != nil {
+			return fmt.Errorf("invalid xpath %q: %w", s.XPath, err)
+		}
+		s.xpath = expr
+	}
+	if s.Regex != "" {
+		re, err := regexp.Compile(s.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", s.Regex, err)
+		}
+		s.regex = re
+	}
+	return nil
+}
+
+// Find returns the value of the first matching element, after regex post-processing
+func (s *Selector) Find(doc *goquery.Document) (string, bool) {
+	return s.FindIn(doc.Selection)
+}
+
+// FindIn is Find restricted to the elements inside scope. XPath selectors
+// should start with "." to be relative to scope.
+func (s *Selector) FindIn(scope *goquery.Selection) (string, bool) {
+	var value string
+	var found bool
+	switch {
+	case s.CSS != "":
+		sel := scope.Find(s.CSS).First()
+		if sel.Length() == 0 {
+			return "", false
+		}
+		if s.Attr != "" {
+			value, found = sel.Attr(s.Attr)
+		} else {
+			value, found = sel.Text(), true
+		}
+	case s.xpath != nil && len(scope.Nodes) > 0:
+		node := htmlquery.QuerySelector(scope.Nodes[0], s.xpath)
+		if node == nil {
+			return "", false
+		}
+		if s.Attr != "" {
+			value, found = htmlquery.SelectAttr(node, s.Attr), htmlquery.ExistsAttr(node, s.Attr)
+		} else {
+			value, found = htmlquery.InnerText(node), true
+		}
+	}
+	if !found {
+		return "", false
+	}
+
+	value = strings.Join(strings.Fields(value), " ")
+	if s.regex != nil {
+		m := s.regex.FindStringSubmatch(value)
+		if m == nil {
+			return "", false
+		}
+		value = m[0]
+		if len(m) > 1 {
+			value = m[1]
+		}
+	}
+	return value, value != ""
+}
+
+// State reads the stock state of the product on the page; it is "" when the
+// rule can't tell
+func (a *Availability) State(doc *goquery.Document) models.Availability {
+	if a.IsZero() {
+		return ""
+	}
+	text, found := a.Find(doc)
+	if len(a.InStock) == 0 && len(a.OutOfStock) == 0 && len(a.Preorder) == 0 && len(a.Discontinued) == 0 {
+		if found {
+			return models.AvailabilityInStock
+		}
+		return models.AvailabilityOutOfStock
+	}
+	if !found {
+		return ""
+	}
+	text = strings.ToLower(text)
+	for _, check := range []struct {
+		phrases []string
+		state   models.Availability
+	}{
+		{a.Discontinued, models.AvailabilityDiscontinued},
+		{a.OutOfStock, models.AvailabilityOutOfStock},
+		{a.Preorder, models.AvailabilityPreorder},
+		{a.InStock, models.AvailabilityInStock},
+	} {
+		for _, phrase := range check.phrases {
+			if strings.Contains(text, strings.ToLower(phrase)) {
+				return check.state
+			}
+		}
+	}
+	// Only one side given: anything else means the opposite
+	if len(a.InStock) == 0 {
+		return models.AvailabilityInStock
+	}
+	if len(a.OutOfStock) == 0 && len(a.Discontinued) == 0 {
+		return models.AvailabilityOutOfStock
+	}
+	return ""
+}
+
+// compile validates the rule and all of its selectors
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule has no name")
+	}
+	if len(r.Hosts) == 0 {
+		return fmt.Errorf("rule %q has no hosts", r.Name)
+	}
+	for _, h := range r.Hosts {
+		if _, err := path.Match(h, ""); err != nil {
+			return fmt.Errorf("rule %q: invalid host pattern %q: %w", r.Name, h, err)
+		}
+	}
+	selectors := map[string]*Selector{
+		"name_selector":      &r.ProductName,
+		"price":              &r.Price,
+		"old_price":          &r.OldPrice,
+		"discount":           &r.Discount,
+		"image":              &r.Image,
+		"shipping":           &r.Shipping,
+		"availability":       &r.Availability.Selector,
+		"offers.seller":      &r.Offers.Seller,
+		"offers.price":       &r.Offers.Price,
+		"offers.shipping":    &r.Offers.Shipping,
+		"offers.condition":   &r.Offers.Condition,
+		"offers.fulfillment": &r.Offers.Fulfillment,
+	}
+	for field, s := range selectors {
+		if err := s.compile(); err != nil {
+			return fmt.Errorf("rule %q: %s: %w", r.Name, field, err)
+		}
+	}
+	if r.Offers.Item != "" && r.Offers.Price.IsZero() {
+		return fmt.Errorf("rule %q: offers: item is set without a price selector", r.Name)
+	}
+	for i := range r.Expect {
+		if r.Expect[i].IsZero() {
+			return fmt.Errorf("rule %q: expect[%d]: empty selector", r.Name, i)
+		}
+		if err := r.Expect[i].compile(); err != nil {
+			return fmt.Errorf("rule %q: expect[%d]: %w", r.Name, i, err)
+		}
+	}
+	return nil
+}
+
+// MatchesHost reports whether the rule applies to host
+func (r *Rule) MatchesHost(host string) bool {
+	return MatchHost(host, r.Hosts...)
+}
+
+// MatchHost reports whether host matches any of patterns. A plain pattern
+// matches the host and its subdomains; patterns may also use path.Match globs.
+func MatchHost(host string, patterns ...string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if host == pattern || strings.HasSuffix(host, "."+pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// File returns the path of the file the rule was loaded from
+func (r *Rule) File() string { return r.file }
+
+// Set is a collection of rules
+type Set struct {
+	rules []*Rule
+}
+
+// NewSet creates a set from already-built rules, validating each one
+func NewSet(rules ...*Rule) (*Set, error) {
+	for _, r := range rules {
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return &Set{rules: rules}, nil
+}
+
+// LoadDir loads every *.yaml and *.yml file in dir. An empty dir yields an empty set.
+func LoadDir(dir string) (*Set, error) {
+	set := &Set{}
+	if dir == "" {
+		return set, nil
+	}
+
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		rules, err := loadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		set.rules = append(set.rules, rules...)
+	}
+	return set, nil
+}
+
+// loadFile reads a file holding a single rule or a list of rules
+func loadFile(filename string) ([]*Rule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+
+	var rules []*Rule
+	if node.Content[0].Kind == yaml.SequenceNode {
+		if err := node.Content[0].Decode(&rules); err != nil {
+			return nil, err
+		}
+	} else {
+		var r Rule
+		if err := node.Content[0].Decode(&r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &r)
+	}
+
+	for _, r := range rules {
+		r.file = filename
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// Match returns the first rule that applies to host, or nil
+func (s *Set) Match(host string) *Rule {
+	if s == nil {
+		return nil
+	}
+	for _, r := range s.rules {
+		if r.MatchesHost(host) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Len returns the number of rules in the set
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
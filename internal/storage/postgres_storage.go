@@ -47,26 +47,118 @@ func pgEnsureSchema(db *sql.DB) error {
 			currency TEXT NOT NULL DEFAULT 'BRL',
 			is_available BOOLEAN NOT NULL DEFAULT TRUE,
 			website TEXT NOT NULL DEFAULT '',
+			price_source TEXT NOT NULL DEFAULT '',
+			fetcher TEXT NOT NULL DEFAULT '',
+			scrape_status TEXT NOT NULL DEFAULT '',
+			etag TEXT NOT NULL DEFAULT '',
+			last_modified TEXT NOT NULL DEFAULT '',
+			variants TEXT NOT NULL DEFAULT '',
+			variant_sku TEXT NOT NULL DEFAULT '',
+			variant_attributes TEXT NOT NULL DEFAULT '',
+			shipping_cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+			free_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+			list_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+			discount_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+			availability TEXT NOT NULL DEFAULT 'unknown',
+			schedule TEXT NOT NULL DEFAULT '',
+			next_check_at TIMESTAMPTZ,
 			created_at TIMESTAMPTZ NOT NULL,
 			updated_at TIMESTAMPTZ NOT NULL
 		)`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS price_source TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS fetcher TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS scrape_status TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS etag TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS last_modified TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS variants TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS variant_sku TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS variant_attributes TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS shipping_cost DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS list_price DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS discount_percent DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS availability TEXT NOT NULL DEFAULT 'unknown'`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS schedule TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS next_check_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_products_next_check_at ON products(next_check_at)`,
+		`CREATE TABLE IF NOT EXISTS site_schedules (
+			host TEXT PRIMARY KEY,
+			schedule TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS scrape_jobs (
+			product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+			next_run_at TIMESTAMPTZ NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			lease_owner TEXT NOT NULL DEFAULT '',
+			lease_expires_at TIMESTAMPTZ,
+			last_error TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_scrape_jobs_next_run_at ON scrape_jobs(next_run_at)`,
+		`INSERT INTO scrape_jobs (product_id, next_run_at, updated_at)
+			SELECT id, COALESCE(next_check_at, NOW()), NOW() FROM products
+			ON CONFLICT (product_id) DO NOTHING`,
 		`CREATE TABLE IF NOT EXISTS price_history (
 			id UUID PRIMARY KEY,
 			product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
 			price DOUBLE PRECISION NOT NULL,
+			shipping DOUBLE PRECISION NOT NULL DEFAULT 0,
+			free_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+			list_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+			discount_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+			availability TEXT NOT NULL DEFAULT '',
 			created_at TIMESTAMPTZ NOT NULL
 		)`,
+		`ALTER TABLE price_history ADD COLUMN IF NOT EXISTS shipping DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE price_history ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE price_history ADD COLUMN IF NOT EXISTS list_price DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE price_history ADD COLUMN IF NOT EXISTS discount_percent DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE price_history ADD COLUMN IF NOT EXISTS availability TEXT NOT NULL DEFAULT ''`,
 		`CREATE INDEX IF NOT EXISTS idx_price_history_product_id ON price_history(product_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_price_history_created_at ON price_history(created_at)`,
+		`CREATE TABLE IF NOT EXISTS offers (
+			id UUID PRIMARY KEY,
+			product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+			seller TEXT NOT NULL DEFAULT '',
+			price DOUBLE PRECISION NOT NULL,
+			shipping DOUBLE PRECISION NOT NULL DEFAULT 0,
+			free_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+			currency TEXT NOT NULL DEFAULT '',
+			condition TEXT NOT NULL DEFAULT '',
+			fulfillment TEXT NOT NULL DEFAULT '',
+			buy_box BOOLEAN NOT NULL DEFAULT FALSE,
+			is_available BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMPTZ NOT NULL
+		)`,
+		`ALTER TABLE offers ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE INDEX IF NOT EXISTS idx_offers_product_id_created_at ON offers(product_id, created_at)`,
+		`CREATE TABLE IF NOT EXISTS scrape_runs (
+			id UUID PRIMARY KEY,
+			product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			price DOUBLE PRECISION NOT NULL DEFAULT 0,
+			availability TEXT NOT NULL DEFAULT '',
+			started_at TIMESTAMPTZ NOT NULL,
+			duration_ms BIGINT NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_scrape_runs_product_id_started_at ON scrape_runs(product_id, started_at)`,
 		`CREATE TABLE IF NOT EXISTS alerts (
 			id UUID PRIMARY KEY,
 			product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
 			target_price DOUBLE PRECISION NOT NULL,
 			is_active BOOLEAN NOT NULL DEFAULT TRUE,
 			notification_type TEXT NOT NULL,
+			use_total_price BOOLEAN NOT NULL DEFAULT FALSE,
+			skip_fake_discount BOOLEAN NOT NULL DEFAULT FALSE,
+			kind TEXT NOT NULL DEFAULT 'price',
 			created_at TIMESTAMPTZ NOT NULL,
 			notified_at TIMESTAMPTZ
 		)`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS use_total_price BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS skip_fake_discount BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'price'`,
 		`CREATE INDEX IF NOT EXISTS idx_alerts_product_id ON alerts(product_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_alerts_is_active ON alerts(is_active)`,
 	}
@@ -93,38 +185,39 @@ func (s *PostgresStorage) CreateProduct(ctx context.Context, p *models.Product)
 	now := time.Now()
 	if p.CreatedAt.IsZero() { p.CreatedAt = now }
 	p.UpdatedAt = now
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO products (id, name, url, image_url, current_price, currency, is_available, website, created_at, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
-	`, p.ID, p.Name, p.URL, p.ImageURL, p.CurrentPrice, p.Currency, p.IsAvailable, p.Website, p.CreatedAt, p.UpdatedAt)
-	return err
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO products (id, name, url, image_url, current_price, currency, is_available, website, price_source, fetcher, scrape_status, etag, last_modified, variants, variant_sku, variant_attributes, shipping_cost, free_shipping, list_price, discount_percent, availability, schedule, next_check_at, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25)
+	`, p.ID, p.Name, p.URL, p.ImageURL, p.CurrentPrice, p.Currency, p.IsAvailable, p.Website, p.PriceSource, p.Fetcher, p.ScrapeStatus, p.ETag, p.LastModified, jsonText(p.Variants), p.VariantSKU, jsonText(p.VariantAttributes), p.ShippingCost, p.FreeShipping, p.ListPrice, p.DiscountPercent, p.Availability, p.Schedule, nullPGTime(p.NextCheckAt), p.CreatedAt, p.UpdatedAt)
+	if err != nil { return err }
+	if err := pgEnqueueJob(ctx, tx, p.ID, p.NextCheckAt); err != nil { return err }
+	return tx.Commit()
 }
 
 // GetProductByID implements Storage.GetProductByID
 func (s *PostgresStorage) GetProductByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
-	var p models.Product
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, url, image_url, current_price, currency, is_available, website, created_at, updated_at
-		FROM products WHERE id = $1
-	`, id).Scan(&p.ID, &p.Name, &p.URL, &p.ImageURL, &p.CurrentPrice, &p.Currency, &p.IsAvailable, &p.Website, &p.CreatedAt, &p.UpdatedAt)
+	p, err := scanProduct(s.db.QueryRowContext(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1`, id))
 	if err == sql.ErrNoRows { return nil, nil }
 	if err != nil { return nil, err }
-	return &p, nil
+	return p, nil
 }
 
 // UpdateProduct implements Storage.UpdateProduct
 func (s *PostgresStorage) UpdateProduct(ctx context.Context, p *models.Product) error {
 	p.UpdatedAt = time.Now()
 	_, err := s.db.ExecContext(ctx, `
-		UPDATE products SET name=$1, url=$2, image_url=$3, current_price=$4, currency=$5, is_available=$6, website=$7, updated_at=$8
-		WHERE id=$9
-	`, p.Name, p.URL, p.ImageURL, p.CurrentPrice, p.Currency, p.IsAvailable, p.Website, p.UpdatedAt, p.ID)
+		UPDATE products SET name=$1, url=$2, image_url=$3, current_price=$4, currency=$5, is_available=$6, website=$7, price_source=$8, fetcher=$9, scrape_status=$10, etag=$11, last_modified=$12, variants=$13, variant_sku=$14, variant_attributes=$15, shipping_cost=$16, free_shipping=$17, list_price=$18, discount_percent=$19, availability=$20, schedule=$21, updated_at=$22
+		WHERE id=$23
+	`, p.Name, p.URL, p.ImageURL, p.CurrentPrice, p.Currency, p.IsAvailable, p.Website, p.PriceSource, p.Fetcher, p.ScrapeStatus, p.ETag, p.LastModified, jsonText(p.Variants), p.VariantSKU, jsonText(p.VariantAttributes), p.ShippingCost, p.FreeShipping, p.ListPrice, p.DiscountPercent, p.Availability, p.Schedule, p.UpdatedAt, p.ID)
 	return err
 }
 
 // ListProducts implements Storage.ListProducts
 func (s *PostgresStorage) ListProducts(ctx context.Context, limit, offset int) ([]*models.Product, error) {
-	query := `SELECT id, name, url, image_url, current_price, currency, is_available, website, created_at, updated_at FROM products ORDER BY created_at DESC`
+	query := `SELECT ` + productColumns + ` FROM products ORDER BY created_at DESC`
 	args := []any{}
 	if limit > 0 { query += " LIMIT $1"; args = append(args, limit) }
 	if offset > 0 {
@@ -136,11 +229,11 @@ func (s *PostgresStorage) ListProducts(ctx context.Context, limit, offset int) (
 	defer rows.Close()
 	var out []*models.Product
 	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.URL, &p.ImageURL, &p.CurrentPrice, &p.Currency, &p.IsAvailable, &p.Website, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
-		out = append(out, &p)
+		out = append(out, p)
 	}
 	return out, rows.Err()
 }
@@ -151,17 +244,144 @@ func (s *PostgresStorage) DeleteProduct(ctx context.Context, id uuid.UUID) error
 	return err
 }
 
+// LeaseJobs implements Storage.LeaseJobs. SKIP LOCKED lets concurrent
+// callers lease disjoint batches without waiting on each other.
+func (s *PostgresStorage) LeaseJobs(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]*models.ScrapeJob, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE scrape_jobs SET lease_owner=$1, lease_expires_at=$2, attempts=attempts+1, updated_at=$3
+		WHERE product_id IN (
+			SELECT j.product_id FROM scrape_jobs j JOIN products p ON p.id = j.product_id
+			WHERE p.url <> '' AND j.next_run_at <= $3 AND (j.lease_expires_at IS NULL OR j.lease_expires_at <= $3)
+			ORDER BY j.next_run_at LIMIT $4
+			FOR UPDATE OF j SKIP LOCKED
+		)
+		RETURNING `+scrapeJobColumns, owner, now.Add(lease), now, limit)
+	if err != nil { return nil, err }
+	defer rows.Close()
+	var out []*models.ScrapeJob
+	for rows.Next() {
+		job, err := scanScrapeJob(rows)
+		if err != nil { return nil, err }
+		out = append(out, job)
+	}
+	if err := rows.Err(); err != nil { return nil, err }
+	sortJobs(out)
+	return out, nil
+}
+
+// RenewLease implements Storage.RenewLease
+func (s *PostgresStorage) RenewLease(ctx context.Context, productID uuid.UUID, owner string, until time.Time) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE scrape_jobs SET lease_expires_at=$1, updated_at=$2 WHERE product_id=$3 AND lease_owner=$4
+	`, until, time.Now(), productID, owner)
+	if err != nil { return err }
+	n, err := res.RowsAffected()
+	if err != nil { return err }
+	if n == 0 { return ErrLeaseLost }
+	return nil
+}
+
+// ReleaseJob implements Storage.ReleaseJob
+func (s *PostgresStorage) ReleaseJob(ctx context.Context, productID uuid.UUID, owner string, nextRunAt time.Time, lastError string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `
+		UPDATE scrape_jobs SET next_run_at=$1, attempts=CASE WHEN $2 = '' THEN 0 ELSE attempts END, last_error=$2,
+			lease_owner='', lease_expires_at=NULL, updated_at=$3
+		WHERE product_id=$4 AND lease_owner IN ('', $5)
+	`, nextRunAt, lastError, time.Now(), productID, owner)
+	if err != nil { return err }
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM scrape_jobs WHERE product_id=$1)`, productID).Scan(&exists)
+		if err != nil { return err }
+		if exists { return ErrLeaseLost }
+		return nil // the product was deleted meanwhile
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET next_check_at=$1 WHERE id=$2`, nextRunAt, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetNextCheck implements Storage.SetNextCheck
+func (s *PostgresStorage) SetNextCheck(ctx context.Context, productID uuid.UUID, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+	if err := pgEnqueueJob(ctx, tx, productID, at); err != nil { return err }
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET next_check_at=$1 WHERE id=$2`, nullPGTime(at), productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pgEnqueueJob creates or moves the product's job to run at at, or right
+// away when at is zero
+func pgEnqueueJob(ctx context.Context, tx *sql.Tx, productID uuid.UUID, at time.Time) error {
+	now := time.Now()
+	if at.IsZero() { at = now }
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO scrape_jobs (product_id, next_run_at, updated_at) VALUES ($1,$2,$3)
+		ON CONFLICT (product_id) DO UPDATE SET next_run_at=EXCLUDED.next_run_at, updated_at=EXCLUDED.updated_at
+	`, productID, at, now)
+	return err
+}
+
+// SetSiteSchedule implements Storage.SetSiteSchedule
+func (s *PostgresStorage) SetSiteSchedule(ctx context.Context, sched *models.SiteSchedule) error {
+	sched.UpdatedAt = time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO site_schedules (`+siteScheduleColumns+`) VALUES ($1,$2,$3)
+		ON CONFLICT (host) DO UPDATE SET schedule=EXCLUDED.schedule, updated_at=EXCLUDED.updated_at
+	`, sched.Host, sched.Schedule, sched.UpdatedAt)
+	return err
+}
+
+// GetSiteSchedule implements Storage.GetSiteSchedule
+func (s *PostgresStorage) GetSiteSchedule(ctx context.Context, host string) (*models.SiteSchedule, error) {
+	sched, err := scanSiteSchedule(s.db.QueryRowContext(ctx, `SELECT `+siteScheduleColumns+` FROM site_schedules WHERE host=$1`, host))
+	if err == sql.ErrNoRows { return nil, nil }
+	if err != nil { return nil, err }
+	return sched, nil
+}
+
+// ListSiteSchedules implements Storage.ListSiteSchedules
+func (s *PostgresStorage) ListSiteSchedules(ctx context.Context) ([]*models.SiteSchedule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+siteScheduleColumns+` FROM site_schedules ORDER BY host`)
+	if err != nil { return nil, err }
+	defer rows.Close()
+	var out []*models.SiteSchedule
+	for rows.Next() {
+		sched, err := scanSiteSchedule(rows)
+		if err != nil { return nil, err }
+		out = append(out, sched)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSiteSchedule implements Storage.DeleteSiteSchedule
+func (s *PostgresStorage) DeleteSiteSchedule(ctx context.Context, host string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM site_schedules WHERE host=$1`, host)
+	return err
+}
+
 // AddPriceHistory implements Storage.AddPriceHistory
-func (s *PostgresStorage) AddPriceHistory(ctx context.Context, productID uuid.UUID, price float64) error {
+func (s *PostgresStorage) AddPriceHistory(ctx context.Context, e *models.PriceHistory) error {
+	if e.ID == uuid.Nil { e.ID = uuid.New() }
+	if e.CreatedAt.IsZero() { e.CreatedAt = time.Now() }
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO price_history (id, product_id, price, created_at) VALUES ($1,$2,$3,$4)
-	`, uuid.New(), productID, price, time.Now())
+		INSERT INTO price_history (`+priceHistoryColumns+`) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, e.ID, e.ProductID, e.Price, e.Shipping, e.FreeShipping, e.ListPrice, e.DiscountPercent, e.Availability, e.CreatedAt)
 	return err
 }
 
 // GetPriceHistory implements Storage.GetPriceHistory
 func (s *PostgresStorage) GetPriceHistory(ctx context.Context, productID uuid.UUID, sinceDays int) ([]*models.PriceHistory, error) {
-	query := `SELECT id, product_id, price, created_at FROM price_history WHERE product_id=$1`
+	query := `SELECT ` + priceHistoryColumns + ` FROM price_history WHERE product_id=$1`
 	args := []any{productID}
 	if sinceDays > 0 { query += " AND created_at >= $2"; args = append(args, time.Now().Add(-time.Duration(sinceDays)*24*time.Hour)) }
 	query += " ORDER BY created_at DESC"
@@ -170,9 +390,84 @@ func (s *PostgresStorage) GetPriceHistory(ctx context.Context, productID uuid.UU
 	defer rows.Close()
 	var out []*models.PriceHistory
 	for rows.Next() {
-		var ph models.PriceHistory
-		if err := rows.Scan(&ph.ID, &ph.ProductID, &ph.Price, &ph.CreatedAt); err != nil { return nil, err }
-		out = append(out, &ph)
+		ph, err := scanPriceHistory(rows)
+		if err != nil { return nil, err }
+		out = append(out, ph)
+	}
+	return out, rows.Err()
+}
+
+// AddOffers implements Storage.AddOffers
+func (s *PostgresStorage) AddOffers(ctx context.Context, productID uuid.UUID, offers []models.Offer) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+	now := time.Now()
+	for _, o := range offers {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO offers (`+offerColumns+`)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+		`, uuid.New(), productID, o.Seller, o.Price, o.Shipping, o.FreeShipping, o.Currency, o.Condition, o.Fulfillment, o.BuyBox, o.IsAvailable, now)
+		if err != nil { return err }
+	}
+	return tx.Commit()
+}
+
+// GetOffers implements Storage.GetOffers
+func (s *PostgresStorage) GetOffers(ctx context.Context, productID uuid.UUID, sinceDays int) ([]*models.Offer, error) {
+	query := `SELECT ` + offerColumns + ` FROM offers WHERE product_id=$1`
+	args := []any{productID}
+	if sinceDays > 0 { query += " AND created_at >= $2"; args = append(args, time.Now().Add(-time.Duration(sinceDays)*24*time.Hour)) }
+	query += " ORDER BY created_at DESC, price"
+	return s.queryOffers(ctx, query, args...)
+}
+
+// GetLatestOffers implements Storage.GetLatestOffers
+func (s *PostgresStorage) GetLatestOffers(ctx context.Context, productID uuid.UUID) ([]*models.Offer, error) {
+	return s.queryOffers(ctx, `
+		SELECT `+offerColumns+` FROM offers
+		WHERE product_id=$1 AND created_at = (SELECT MAX(created_at) FROM offers WHERE product_id=$1)
+		ORDER BY price
+	`, productID)
+}
+
+// queryOffers runs a query selecting offerColumns
+func (s *PostgresStorage) queryOffers(ctx context.Context, query string, args ...any) ([]*models.Offer, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil { return nil, err }
+	defer rows.Close()
+	var out []*models.Offer
+	for rows.Next() {
+		o, err := scanOffer(rows)
+		if err != nil { return nil, err }
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// AddScrapeRun implements Storage.AddScrapeRun
+func (s *PostgresStorage) AddScrapeRun(ctx context.Context, r *models.ScrapeRun) error {
+	if r.ID == uuid.Nil { r.ID = uuid.New() }
+	if r.StartedAt.IsZero() { r.StartedAt = time.Now() }
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO scrape_runs (`+scrapeRunColumns+`) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+	`, r.ID, r.ProductID, r.Status, r.Error, r.Price, r.Availability, r.StartedAt, r.DurationMs)
+	return err
+}
+
+// GetScrapeRuns implements Storage.GetScrapeRuns
+func (s *PostgresStorage) GetScrapeRuns(ctx context.Context, productID uuid.UUID, limit int) ([]*models.ScrapeRun, error) {
+	query := `SELECT ` + scrapeRunColumns + ` FROM scrape_runs WHERE product_id=$1 ORDER BY started_at DESC`
+	args := []any{productID}
+	if limit > 0 { query += " LIMIT $2"; args = append(args, limit) }
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil { return nil, err }
+	defer rows.Close()
+	var out []*models.ScrapeRun
+	for rows.Next() {
+		r, err := scanScrapeRun(rows)
+		if err != nil { return nil, err }
+		out = append(out, r)
 	}
 	return out, rows.Err()
 }
@@ -181,28 +476,25 @@ func (s *PostgresStorage) GetPriceHistory(ctx context.Context, productID uuid.UU
 func (s *PostgresStorage) CreateAlert(ctx context.Context, a *models.Alert) error {
 	if a.ID == uuid.Nil { a.ID = uuid.New() }
 	if a.CreatedAt.IsZero() { a.CreatedAt = time.Now() }
+	if a.Kind == "" { a.Kind = models.AlertKindPrice }
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO alerts (id, product_id, target_price, is_active, notification_type, created_at, notified_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7)
-	`, a.ID, a.ProductID, a.TargetPrice, a.IsActive, a.NotificationType, a.CreatedAt, nullPGTime(a.NotifiedAt))
+		INSERT INTO alerts (`+alertColumns+`)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	`, a.ID, a.ProductID, a.TargetPrice, a.IsActive, a.NotificationType, a.UseTotalPrice, a.SkipFakeDiscount, a.Kind, a.CreatedAt, nullPGTime(a.NotifiedAt))
 	return err
 }
 
 // GetAlertByID implements Storage.GetAlertByID
 func (s *PostgresStorage) GetAlertByID(ctx context.Context, id uuid.UUID) (*models.Alert, error) {
-	var a models.Alert
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, product_id, target_price, is_active, notification_type, created_at, notified_at
-		FROM alerts WHERE id=$1
-	`, id).Scan(&a.ID, &a.ProductID, &a.TargetPrice, &a.IsActive, &a.NotificationType, &a.CreatedAt, &a.NotifiedAt)
+	a, err := scanAlert(s.db.QueryRowContext(ctx, `SELECT `+alertColumns+` FROM alerts WHERE id=$1`, id))
 	if err == sql.ErrNoRows { return nil, nil }
 	if err != nil { return nil, err }
-	return &a, nil
+	return a, nil
 }
 
 // ListAlerts implements Storage.ListAlerts
 func (s *PostgresStorage) ListAlerts(ctx context.Context, limit, offset int) ([]*models.Alert, error) {
-	query := `SELECT id, product_id, target_price, is_active, notification_type, created_at, notified_at FROM alerts ORDER BY created_at DESC`
+	query := `SELECT ` + alertColumns + ` FROM alerts ORDER BY created_at DESC`
 	args := []any{}
 	if limit > 0 { query += " LIMIT $1"; args = append(args, limit) }
 	if offset > 0 {
@@ -214,9 +506,9 @@ func (s *PostgresStorage) ListAlerts(ctx context.Context, limit, offset int) ([]
 	defer rows.Close()
 	var out []*models.Alert
 	for rows.Next() {
-		var a models.Alert
-		if err := rows.Scan(&a.ID, &a.ProductID, &a.TargetPrice, &a.IsActive, &a.NotificationType, &a.CreatedAt, &a.NotifiedAt); err != nil { return nil, err }
-		out = append(out, &a)
+		a, err := scanAlert(rows)
+		if err != nil { return nil, err }
+		out = append(out, a)
 	}
 	return out, rows.Err()
 }
@@ -224,16 +516,16 @@ func (s *PostgresStorage) ListAlerts(ctx context.Context, limit, offset int) ([]
 // GetActiveAlertsForProduct implements Storage.GetActiveAlertsForProduct
 func (s *PostgresStorage) GetActiveAlertsForProduct(ctx context.Context, productID uuid.UUID) ([]*models.Alert, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, product_id, target_price, is_active, notification_type, created_at, notified_at
+		SELECT `+alertColumns+`
 		FROM alerts WHERE product_id=$1 AND is_active=TRUE
 	`, productID)
 	if err != nil { return nil, err }
 	defer rows.Close()
 	var out []*models.Alert
 	for rows.Next() {
-		var a models.Alert
-		if err := rows.Scan(&a.ID, &a.ProductID, &a.TargetPrice, &a.IsActive, &a.NotificationType, &a.CreatedAt, &a.NotifiedAt); err != nil { return nil, err }
-		out = append(out, &a)
+		a, err := scanAlert(rows)
+		if err != nil { return nil, err }
+		out = append(out, a)
 	}
 	return out, rows.Err()
 }
@@ -241,12 +533,25 @@ func (s *PostgresStorage) GetActiveAlertsForProduct(ctx context.Context, product
 // UpdateAlert implements Storage.UpdateAlert
 func (s *PostgresStorage) UpdateAlert(ctx context.Context, a *models.Alert) error {
 	_, err := s.db.ExecContext(ctx, `
-		UPDATE alerts SET product_id=$1, target_price=$2, is_active=$3, notification_type=$4, created_at=$5, notified_at=$6
-		WHERE id=$7
-	`, a.ProductID, a.TargetPrice, a.IsActive, a.NotificationType, a.CreatedAt, nullPGTime(a.NotifiedAt), a.ID)
+		UPDATE alerts SET product_id=$1, target_price=$2, is_active=$3, notification_type=$4, use_total_price=$5, skip_fake_discount=$6, kind=$7, created_at=$8, notified_at=$9
+		WHERE id=$10
+	`, a.ProductID, a.TargetPrice, a.IsActive, a.NotificationType, a.UseTotalPrice, a.SkipFakeDiscount, a.Kind, a.CreatedAt, nullPGTime(a.NotifiedAt), a.ID)
 	return err
 }
 
+// ClaimAlert implements Storage.ClaimAlert. The row lock taken by the
+// UPDATE makes a concurrent claim wait and then see the new notified_at.
+func (s *PostgresStorage) ClaimAlert(ctx context.Context, id uuid.UUID, since, now time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE alerts SET notified_at=$1
+		WHERE id=$2 AND is_active=TRUE AND (notified_at IS NULL OR notified_at < $3)
+	`, now, id, since)
+	if err != nil { return false, err }
+	n, err := res.RowsAffected()
+	if err != nil { return false, err }
+	return n > 0, nil
+}
+
 // DeleteAlert implements Storage.DeleteAlert
 func (s *PostgresStorage) DeleteAlert(ctx context.Context, id uuid.UUID) error {
 	_, err := s.db.ExecContext(ctx, `DELETE FROM alerts WHERE id=$1`, id)
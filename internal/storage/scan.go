@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+)
+
+// productColumns lists the products columns in the order scanProduct expects
+const productColumns = `id, name, url, image_url, current_price, currency, is_available, website, price_source, fetcher, scrape_status, etag, last_modified, variants, variant_sku, variant_attributes, shipping_cost, free_shipping, list_price, discount_percent, availability, schedule, next_check_at, created_at, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProduct reads a row selected with productColumns
+func scanProduct(row rowScanner) (*models.Product, error) {
+	var p models.Product
+	var variants, variantAttributes string
+	var nextCheckAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.URL, &p.ImageURL, &p.CurrentPrice, &p.Currency, &p.IsAvailable, &p.Website, &p.PriceSource, &p.Fetcher, &p.ScrapeStatus, &p.ETag, &p.LastModified, &variants, &p.VariantSKU, &variantAttributes, &p.ShippingCost, &p.FreeShipping, &p.ListPrice, &p.DiscountPercent, &p.Availability, &p.Schedule, &nextCheckAt, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.NextCheckAt = nextCheckAt.Time
+	if err := parseJSONText(variants, &p.Variants); err != nil {
+		return nil, fmt.Errorf("invalid variants of product %s: %w", p.ID, err)
+	}
+	if err := parseJSONText(variantAttributes, &p.VariantAttributes); err != nil {
+		return nil, fmt.Errorf("invalid variant attributes of product %s: %w", p.ID, err)
+	}
+	return &p, nil
+}
+
+// priceHistoryColumns lists the price_history columns in the order scanPriceHistory expects
+const priceHistoryColumns = `id, product_id, price, shipping, free_shipping, list_price, discount_percent, availability, created_at`
+
+// scanPriceHistory reads a row selected with priceHistoryColumns
+func scanPriceHistory(row rowScanner) (*models.PriceHistory, error) {
+	var h models.PriceHistory
+	if err := row.Scan(&h.ID, &h.ProductID, &h.Price, &h.Shipping, &h.FreeShipping, &h.ListPrice, &h.DiscountPercent, &h.Availability, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// alertColumns lists the alerts columns in the order scanAlert expects
+const alertColumns = `id, product_id, target_price, is_active, notification_type, use_total_price, skip_fake_discount, kind, created_at, notified_at`
+
+// scanAlert reads a row selected with alertColumns
+func scanAlert(row rowScanner) (*models.Alert, error) {
+	var a models.Alert
+	var notifiedAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.ProductID, &a.TargetPrice, &a.IsActive, &a.NotificationType, &a.UseTotalPrice, &a.SkipFakeDiscount, &a.Kind, &a.CreatedAt, &notifiedAt); err != nil {
+		return nil, err
+	}
+	// notified_at is NULL until the alert first fires
+	a.NotifiedAt = notifiedAt.Time
+	return &a, nil
+}
+
+// offerColumns lists the offers columns in the order scanOffer expects
+const offerColumns = `id, product_id, seller, price, shipping, free_shipping, currency, condition, fulfillment, buy_box, is_available, created_at`
+
+// scanOffer reads a row selected with offerColumns
+func scanOffer(row rowScanner) (*models.Offer, error) {
+	var o models.Offer
+	if err := row.Scan(&o.ID, &o.ProductID, &o.Seller, &o.Price, &o.Shipping, &o.FreeShipping, &o.Currency, &o.Condition, &o.Fulfillment, &o.BuyBox, &o.IsAvailable, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// scrapeJobColumns lists the scrape_jobs columns in the order scanScrapeJob expects
+const scrapeJobColumns = `product_id, next_run_at, attempts, lease_owner, lease_expires_at, last_error, updated_at`
+
+// scanScrapeJob reads a row selected with scrapeJobColumns
+func scanScrapeJob(row rowScanner) (*models.ScrapeJob, error) {
+	var j models.ScrapeJob
+	var leaseExpiresAt sql.NullTime
+	if err := row.Scan(&j.ProductID, &j.NextRunAt, &j.Attempts, &j.LeaseOwner, &leaseExpiresAt, &j.LastError, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	j.LeaseExpiresAt = leaseExpiresAt.Time
+	return &j, nil
+}
+
+// sortJobs orders jobs by due time, since UPDATE ... RETURNING doesn't keep
+// the subquery's order
+func sortJobs(jobs []*models.ScrapeJob) {
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].NextRunAt.Before(jobs[j].NextRunAt) })
+}
+
+// siteScheduleColumns lists the site_schedules columns in the order scanSiteSchedule expects
+const siteScheduleColumns = `host, schedule, updated_at`
+
+// scanSiteSchedule reads a row selected with siteScheduleColumns
+func scanSiteSchedule(row rowScanner) (*models.SiteSchedule, error) {
+	var s models.SiteSchedule
+	if err := row.Scan(&s.Host, &s.Schedule, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// scrapeRunColumns lists the scrape_runs columns in the order scanScrapeRun expects
+const scrapeRunColumns = `id, product_id, status, error, price, availability, started_at, duration_ms`
+
+// scanScrapeRun reads a row selected with scrapeRunColumns
+func scanScrapeRun(row rowScanner) (*models.ScrapeRun, error) {
+	var r models.ScrapeRun
+	if err := row.Scan(&r.ID, &r.ProductID, &r.Status, &r.Error, &r.Price, &r.Availability, &r.StartedAt, &r.DurationMs); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// jsonText encodes a slice or map for a TEXT column; empty values are stored as ''
+func jsonText(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" || string(data) == "[]" || string(data) == "{}" {
+		return ""
+	}
+	return string(data)
+}
+
+// parseJSONText decodes a TEXT column written by jsonText
+func parseJSONText(s string, v any) error {
+	if s == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(s), v)
+}
@@ -34,6 +34,11 @@ func NewSQLiteStorage(dsn string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	// Bring databases created by older versions up to date
+	if err := migrateTables(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate tables: %w", err)
+	}
+
 	return &SQLiteStorage{db: db}, nil
 }
 
@@ -49,14 +54,53 @@ func createTables(db *sql.DB) error {
 			currency TEXT NOT NULL DEFAULT 'BRL',
 			is_available INTEGER NOT NULL DEFAULT 1,
 			website TEXT NOT NULL DEFAULT '',
+			price_source TEXT NOT NULL DEFAULT '',
+			fetcher TEXT NOT NULL DEFAULT '',
+			scrape_status TEXT NOT NULL DEFAULT '',
+			etag TEXT NOT NULL DEFAULT '',
+			last_modified TEXT NOT NULL DEFAULT '',
+			variants TEXT NOT NULL DEFAULT '',
+			variant_sku TEXT NOT NULL DEFAULT '',
+			variant_attributes TEXT NOT NULL DEFAULT '',
+			shipping_cost REAL NOT NULL DEFAULT 0,
+			free_shipping INTEGER NOT NULL DEFAULT 0,
+			list_price REAL NOT NULL DEFAULT 0,
+			discount_percent REAL NOT NULL DEFAULT 0,
+			availability TEXT NOT NULL DEFAULT 'unknown',
+			schedule TEXT NOT NULL DEFAULT '',
+			next_check_at TIMESTAMP,
 			created_at TIMESTAMP NOT NULL,
 			updated_at TIMESTAMP NOT NULL
 		);
 
+		CREATE TABLE IF NOT EXISTS site_schedules (
+			host TEXT PRIMARY KEY,
+			schedule TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS scrape_jobs (
+			product_id TEXT PRIMARY KEY,
+			next_run_at TIMESTAMP NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			lease_owner TEXT NOT NULL DEFAULT '',
+			lease_expires_at TIMESTAMP,
+			last_error TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_scrape_jobs_next_run_at ON scrape_jobs(next_run_at);
+
 		CREATE TABLE IF NOT EXISTS price_history (
 			id TEXT PRIMARY KEY,
 			product_id TEXT NOT NULL,
 			price REAL NOT NULL,
+			shipping REAL NOT NULL DEFAULT 0,
+			free_shipping INTEGER NOT NULL DEFAULT 0,
+			list_price REAL NOT NULL DEFAULT 0,
+			discount_percent REAL NOT NULL DEFAULT 0,
+			availability TEXT NOT NULL DEFAULT '',
 			created_at TIMESTAMP NOT NULL,
 			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
 		);
@@ -64,12 +108,47 @@ func createTables(db *sql.DB) error {
 		CREATE INDEX IF NOT EXISTS idx_price_history_product_id ON price_history(product_id);
 		CREATE INDEX IF NOT EXISTS idx_price_history_created_at ON price_history(created_at);
 
+		CREATE TABLE IF NOT EXISTS offers (
+			id TEXT PRIMARY KEY,
+			product_id TEXT NOT NULL,
+			seller TEXT NOT NULL DEFAULT '',
+			price REAL NOT NULL,
+			shipping REAL NOT NULL DEFAULT 0,
+			free_shipping INTEGER NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL DEFAULT '',
+			condition TEXT NOT NULL DEFAULT '',
+			fulfillment TEXT NOT NULL DEFAULT '',
+			buy_box INTEGER NOT NULL DEFAULT 0,
+			is_available INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_offers_product_id_created_at ON offers(product_id, created_at);
+
+		CREATE TABLE IF NOT EXISTS scrape_runs (
+			id TEXT PRIMARY KEY,
+			product_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			price REAL NOT NULL DEFAULT 0,
+			availability TEXT NOT NULL DEFAULT '',
+			started_at TIMESTAMP NOT NULL,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_scrape_runs_product_id_started_at ON scrape_runs(product_id, started_at);
+
 		CREATE TABLE IF NOT EXISTS alerts (
 			id TEXT PRIMARY KEY,
 			product_id TEXT NOT NULL,
 			target_price REAL NOT NULL,
 			is_active INTEGER NOT NULL DEFAULT 1,
 			notification_type TEXT NOT NULL,
+			use_total_price INTEGER NOT NULL DEFAULT 0,
+			skip_fake_discount INTEGER NOT NULL DEFAULT 0,
+			kind TEXT NOT NULL DEFAULT 'price',
 			created_at TIMESTAMP NOT NULL,
 			notified_at TIMESTAMP,
 			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
@@ -81,6 +160,68 @@ func createTables(db *sql.DB) error {
 	return err
 }
 
+// migrateTables adds columns introduced after the initial schema, along with
+// the indexes and rows that depend on them
+func migrateTables(db *sql.DB) error {
+	columns := []struct{ table, column, def string }{
+		{"products", "price_source", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "fetcher", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "scrape_status", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "etag", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "last_modified", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "variants", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "variant_sku", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "variant_attributes", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "shipping_cost", "REAL NOT NULL DEFAULT 0"},
+		{"products", "free_shipping", "INTEGER NOT NULL DEFAULT 0"},
+		{"price_history", "shipping", "REAL NOT NULL DEFAULT 0"},
+		{"price_history", "free_shipping", "INTEGER NOT NULL DEFAULT 0"},
+		{"offers", "free_shipping", "INTEGER NOT NULL DEFAULT 0"},
+		{"alerts", "use_total_price", "INTEGER NOT NULL DEFAULT 0"},
+		{"products", "list_price", "REAL NOT NULL DEFAULT 0"},
+		{"products", "discount_percent", "REAL NOT NULL DEFAULT 0"},
+		{"price_history", "list_price", "REAL NOT NULL DEFAULT 0"},
+		{"price_history", "discount_percent", "REAL NOT NULL DEFAULT 0"},
+		{"alerts", "skip_fake_discount", "INTEGER NOT NULL DEFAULT 0"},
+		{"products", "availability", "TEXT NOT NULL DEFAULT 'unknown'"},
+		{"price_history", "availability", "TEXT NOT NULL DEFAULT ''"},
+		{"alerts", "kind", "TEXT NOT NULL DEFAULT 'price'"},
+		{"products", "schedule", "TEXT NOT NULL DEFAULT ''"},
+		{"products", "next_check_at", "TIMESTAMP"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.def); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_products_next_check_at ON products(next_check_at)`); err != nil {
+		return err
+	}
+
+	// Products created before the scrape queue existed get their job here
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO scrape_jobs (product_id, next_run_at, updated_at)
+		SELECT id, COALESCE(next_check_at, ?), ? FROM products
+	`, now, now)
+	return err
+}
+
+// addColumnIfMissing adds a column to an existing table, since SQLite has no
+// ADD COLUMN IF NOT EXISTS
+func addColumnIfMissing(db *sql.DB, table, column, def string) error {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, def))
+	return err
+}
+
 // Close implements Storage.Close
 func (s *SQLiteStorage) Close() error { return s.db.Close() }
 
@@ -93,39 +234,44 @@ func (s *SQLiteStorage) CreateProduct(ctx context.Context, product *models.Produ
 	if product.CreatedAt.IsZero() { product.CreatedAt = now }
 	product.UpdatedAt = now
 
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO products (id, name, url, image_url, current_price, currency, is_available, website, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO products (id, name, url, image_url, current_price, currency, is_available, website, price_source, fetcher, scrape_status, etag, last_modified, variants, variant_sku, variant_attributes, shipping_cost, free_shipping, list_price, discount_percent, availability, schedule, next_check_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, product.ID.String(), product.Name, product.URL, product.ImageURL, product.CurrentPrice, product.Currency,
-		boolToInt(product.IsAvailable), product.Website, product.CreatedAt, product.UpdatedAt)
-	return err
+		boolToInt(product.IsAvailable), product.Website, product.PriceSource, product.Fetcher, product.ScrapeStatus, product.ETag, product.LastModified,
+		jsonText(product.Variants), product.VariantSKU, jsonText(product.VariantAttributes), product.ShippingCost, boolToInt(product.FreeShipping),
+		product.ListPrice, product.DiscountPercent, product.Availability, product.Schedule, nullTime(product.NextCheckAt), product.CreatedAt, product.UpdatedAt)
+	if err != nil { return err }
+	if err := enqueueJob(ctx, tx, product.ID, product.NextCheckAt); err != nil { return err }
+	return tx.Commit()
 }
 
 // GetProductByID implements Storage.GetProductByID
 func (s *SQLiteStorage) GetProductByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
-	var p models.Product
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, url, image_url, current_price, currency, is_available, website, created_at, updated_at
-		FROM products WHERE id = ?
-	`, id.String()).Scan(&p.ID, &p.Name, &p.URL, &p.ImageURL, &p.CurrentPrice, &p.Currency, &p.IsAvailable, &p.Website, &p.CreatedAt, &p.UpdatedAt)
+	p, err := scanProduct(s.db.QueryRowContext(ctx, `SELECT `+productColumns+` FROM products WHERE id = ?`, id.String()))
 	if err == sql.ErrNoRows { return nil, nil }
 	if err != nil { return nil, err }
-	return &p, nil
+	return p, nil
 }
 
 // UpdateProduct implements Storage.UpdateProduct
 func (s *SQLiteStorage) UpdateProduct(ctx context.Context, product *models.Product) error {
 	product.UpdatedAt = time.Now()
 	_, err := s.db.ExecContext(ctx, `
-		UPDATE products SET name = ?, url = ?, image_url = ?, current_price = ?, currency = ?, is_available = ?, website = ?, updated_at = ?
+		UPDATE products SET name = ?, url = ?, image_url = ?, current_price = ?, currency = ?, is_available = ?, website = ?, price_source = ?, fetcher = ?, scrape_status = ?, etag = ?, last_modified = ?, variants = ?, variant_sku = ?, variant_attributes = ?, shipping_cost = ?, free_shipping = ?, list_price = ?, discount_percent = ?, availability = ?, schedule = ?, updated_at = ?
 		WHERE id = ?
-	`, product.Name, product.URL, product.ImageURL, product.CurrentPrice, product.Currency, boolToInt(product.IsAvailable), product.Website, product.UpdatedAt, product.ID.String())
+	`, product.Name, product.URL, product.ImageURL, product.CurrentPrice, product.Currency, boolToInt(product.IsAvailable), product.Website, product.PriceSource, product.Fetcher, product.ScrapeStatus, product.ETag, product.LastModified, jsonText(product.Variants), product.VariantSKU, jsonText(product.VariantAttributes), product.ShippingCost, boolToInt(product.FreeShipping),
+		product.ListPrice, product.DiscountPercent, product.Availability, product.Schedule, product.UpdatedAt, product.ID.String())
 	return err
 }
 
 // ListProducts implements Storage.ListProducts
 func (s *SQLiteStorage) ListProducts(ctx context.Context, limit, offset int) ([]*models.Product, error) {
-	query := `SELECT id, name, url, image_url, current_price, currency, is_available, website, created_at, updated_at FROM products ORDER BY created_at DESC`
+	query := `SELECT ` + productColumns + ` FROM products ORDER BY created_at DESC`
 	args := []any{}
 	if limit > 0 { query += " LIMIT ?"; args = append(args, limit) }
 	if offset > 0 { query += " OFFSET ?"; args = append(args, offset) }
@@ -136,11 +282,11 @@ func (s *SQLiteStorage) ListProducts(ctx context.Context, limit, offset int) ([]
 
 	var items []*models.Product
 	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.URL, &p.ImageURL, &p.CurrentPrice, &p.Currency, &p.IsAvailable, &p.Website, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
-		items = append(items, &p)
+		items = append(items, p)
 	}
 	return items, rows.Err()
 }
@@ -151,18 +297,149 @@ func (s *SQLiteStorage) DeleteProduct(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// LeaseJobs implements Storage.LeaseJobs. SQLite runs the UPDATE under its
+// database-wide write lock, so concurrent callers never lease the same job.
+func (s *SQLiteStorage) LeaseJobs(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]*models.ScrapeJob, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE scrape_jobs SET lease_owner = ?, lease_expires_at = ?, attempts = attempts + 1, updated_at = ?
+		WHERE product_id IN (
+			SELECT j.product_id FROM scrape_jobs j JOIN products p ON p.id = j.product_id
+			WHERE p.url <> '' AND j.next_run_at <= ? AND (j.lease_expires_at IS NULL OR j.lease_expires_at <= ?)
+			ORDER BY j.next_run_at LIMIT ?
+		)
+		RETURNING `+scrapeJobColumns, owner, now.Add(lease), now, now, now, limit)
+	if err != nil { return nil, err }
+	defer rows.Close()
+
+	var items []*models.ScrapeJob
+	for rows.Next() {
+		job, err := scanScrapeJob(rows)
+		if err != nil { return nil, err }
+		items = append(items, job)
+	}
+	if err := rows.Err(); err != nil { return nil, err }
+	sortJobs(items)
+	return items, nil
+}
+
+// RenewLease implements Storage.RenewLease
+func (s *SQLiteStorage) RenewLease(ctx context.Context, productID uuid.UUID, owner string, until time.Time) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE scrape_jobs SET lease_expires_at = ?, updated_at = ? WHERE product_id = ? AND lease_owner = ?
+	`, until, time.Now(), productID.String(), owner)
+	if err != nil { return err }
+	n, err := res.RowsAffected()
+	if err != nil { return err }
+	if n == 0 { return ErrLeaseLost }
+	return nil
+}
+
+// ReleaseJob implements Storage.ReleaseJob
+func (s *SQLiteStorage) ReleaseJob(ctx context.Context, productID uuid.UUID, owner string, nextRunAt time.Time, lastError string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE scrape_jobs SET next_run_at = ?, attempts = CASE WHEN ? = '' THEN 0 ELSE attempts END, last_error = ?,
+			lease_owner = '', lease_expires_at = NULL, updated_at = ?
+		WHERE product_id = ? AND lease_owner IN ('', ?)
+	`, nextRunAt, lastError, lastError, time.Now(), productID.String(), owner)
+	if err != nil { return err }
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var exists int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM scrape_jobs WHERE product_id = ?`, productID.String()).Scan(&exists)
+		if err != nil { return err }
+		if exists > 0 { return ErrLeaseLost }
+		return nil // the product was deleted meanwhile
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET next_check_at = ? WHERE id = ?`, nextRunAt, productID.String()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetNextCheck implements Storage.SetNextCheck
+func (s *SQLiteStorage) SetNextCheck(ctx context.Context, productID uuid.UUID, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+
+	if err := enqueueJob(ctx, tx, productID, at); err != nil { return err }
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET next_check_at = ? WHERE id = ?`, nullTime(at), productID.String()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// enqueueJob creates or moves the product's job to run at at, or right away
+// when at is zero
+func enqueueJob(ctx context.Context, tx *sql.Tx, productID uuid.UUID, at time.Time) error {
+	now := time.Now()
+	if at.IsZero() { at = now }
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO scrape_jobs (product_id, next_run_at, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (product_id) DO UPDATE SET next_run_at = excluded.next_run_at, updated_at = excluded.updated_at
+	`, productID.String(), at, now)
+	return err
+}
+
+// SetSiteSchedule implements Storage.SetSiteSchedule
+func (s *SQLiteStorage) SetSiteSchedule(ctx context.Context, schedule *models.SiteSchedule) error {
+	schedule.UpdatedAt = time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO site_schedules (`+siteScheduleColumns+`) VALUES (?, ?, ?)
+		ON CONFLICT (host) DO UPDATE SET schedule = excluded.schedule, updated_at = excluded.updated_at
+	`, schedule.Host, schedule.Schedule, schedule.UpdatedAt)
+	return err
+}
+
+// GetSiteSchedule implements Storage.GetSiteSchedule
+func (s *SQLiteStorage) GetSiteSchedule(ctx context.Context, host string) (*models.SiteSchedule, error) {
+	sched, err := scanSiteSchedule(s.db.QueryRowContext(ctx, `SELECT `+siteScheduleColumns+` FROM site_schedules WHERE host = ?`, host))
+	if err == sql.ErrNoRows { return nil, nil }
+	if err != nil { return nil, err }
+	return sched, nil
+}
+
+// ListSiteSchedules implements Storage.ListSiteSchedules
+func (s *SQLiteStorage) ListSiteSchedules(ctx context.Context) ([]*models.SiteSchedule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+siteScheduleColumns+` FROM site_schedules ORDER BY host`)
+	if err != nil { return nil, err }
+	defer rows.Close()
+
+	var items []*models.SiteSchedule
+	for rows.Next() {
+		sched, err := scanSiteSchedule(rows)
+		if err != nil { return nil, err }
+		items = append(items, sched)
+	}
+	return items, rows.Err()
+}
+
+// DeleteSiteSchedule implements Storage.DeleteSiteSchedule
+func (s *SQLiteStorage) DeleteSiteSchedule(ctx context.Context, host string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM site_schedules WHERE host = ?`, host)
+	return err
+}
+
 // AddPriceHistory implements Storage.AddPriceHistory
-func (s *SQLiteStorage) AddPriceHistory(ctx context.Context, productID uuid.UUID, price float64) error {
+func (s *SQLiteStorage) AddPriceHistory(ctx context.Context, entry *models.PriceHistory) error {
+	if entry.ID == uuid.Nil { entry.ID = uuid.New() }
+	if entry.CreatedAt.IsZero() { entry.CreatedAt = time.Now() }
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO price_history (id, product_id, price, created_at)
-		VALUES (?, ?, ?, ?)
-	`, uuid.New().String(), productID.String(), price, time.Now())
+		INSERT INTO price_history (`+priceHistoryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.ID.String(), entry.ProductID.String(), entry.Price, entry.Shipping, boolToInt(entry.FreeShipping),
+		entry.ListPrice, entry.DiscountPercent, entry.Availability, entry.CreatedAt)
 	return err
 }
 
 // GetPriceHistory implements Storage.GetPriceHistory
 func (s *SQLiteStorage) GetPriceHistory(ctx context.Context, productID uuid.UUID, sinceDays int) ([]*models.PriceHistory, error) {
-	query := `SELECT id, product_id, price, created_at FROM price_history WHERE product_id = ?`
+	query := `SELECT ` + priceHistoryColumns + ` FROM price_history WHERE product_id = ?`
 	args := []any{productID.String()}
 	if sinceDays > 0 {
 		query += " AND created_at >= ?"
@@ -176,11 +453,95 @@ func (s *SQLiteStorage) GetPriceHistory(ctx context.Context, productID uuid.UUID
 
 	var items []*models.PriceHistory
 	for rows.Next() {
-		var ph models.PriceHistory
-		if err := rows.Scan(&ph.ID, &ph.ProductID, &ph.Price, &ph.CreatedAt); err != nil {
+		ph, err := scanPriceHistory(rows)
+		if err != nil {
 			return nil, err
 		}
-		items = append(items, &ph)
+		items = append(items, ph)
+	}
+	return items, rows.Err()
+}
+
+// AddOffers implements Storage.AddOffers
+func (s *SQLiteStorage) AddOffers(ctx context.Context, productID uuid.UUID, offers []models.Offer) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, o := range offers {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO offers (`+offerColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), productID.String(), o.Seller, o.Price, o.Shipping, boolToInt(o.FreeShipping), o.Currency, o.Condition, o.Fulfillment,
+			boolToInt(o.BuyBox), boolToInt(o.IsAvailable), now)
+		if err != nil { return err }
+	}
+	return tx.Commit()
+}
+
+// GetOffers implements Storage.GetOffers
+func (s *SQLiteStorage) GetOffers(ctx context.Context, productID uuid.UUID, sinceDays int) ([]*models.Offer, error) {
+	query := `SELECT ` + offerColumns + ` FROM offers WHERE product_id = ?`
+	args := []any{productID.String()}
+	if sinceDays > 0 {
+		query += " AND created_at >= ?"
+		args = append(args, time.Now().Add(-time.Duration(sinceDays)*24*time.Hour))
+	}
+	query += " ORDER BY created_at DESC, price"
+	return s.queryOffers(ctx, query, args...)
+}
+
+// GetLatestOffers implements Storage.GetLatestOffers
+func (s *SQLiteStorage) GetLatestOffers(ctx context.Context, productID uuid.UUID) ([]*models.Offer, error) {
+	return s.queryOffers(ctx, `
+		SELECT `+offerColumns+` FROM offers
+		WHERE product_id = ? AND created_at = (SELECT MAX(created_at) FROM offers WHERE product_id = ?)
+		ORDER BY price
+	`, productID.String(), productID.String())
+}
+
+// queryOffers runs a query selecting offerColumns
+func (s *SQLiteStorage) queryOffers(ctx context.Context, query string, args ...any) ([]*models.Offer, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil { return nil, err }
+	defer rows.Close()
+
+	var items []*models.Offer
+	for rows.Next() {
+		o, err := scanOffer(rows)
+		if err != nil { return nil, err }
+		items = append(items, o)
+	}
+	return items, rows.Err()
+}
+
+// AddScrapeRun implements Storage.AddScrapeRun
+func (s *SQLiteStorage) AddScrapeRun(ctx context.Context, run *models.ScrapeRun) error {
+	if run.ID == uuid.Nil { run.ID = uuid.New() }
+	if run.StartedAt.IsZero() { run.StartedAt = time.Now() }
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO scrape_runs (`+scrapeRunColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.ID.String(), run.ProductID.String(), run.Status, run.Error, run.Price, run.Availability, run.StartedAt, run.DurationMs)
+	return err
+}
+
+// GetScrapeRuns implements Storage.GetScrapeRuns
+func (s *SQLiteStorage) GetScrapeRuns(ctx context.Context, productID uuid.UUID, limit int) ([]*models.ScrapeRun, error) {
+	query := `SELECT ` + scrapeRunColumns + ` FROM scrape_runs WHERE product_id = ? ORDER BY started_at DESC`
+	args := []any{productID.String()}
+	if limit > 0 { query += " LIMIT ?"; args = append(args, limit) }
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil { return nil, err }
+	defer rows.Close()
+
+	var items []*models.ScrapeRun
+	for rows.Next() {
+		r, err := scanScrapeRun(rows)
+		if err != nil { return nil, err }
+		items = append(items, r)
 	}
 	return items, rows.Err()
 }
@@ -189,28 +550,26 @@ func (s *SQLiteStorage) GetPriceHistory(ctx context.Context, productID uuid.UUID
 func (s *SQLiteStorage) CreateAlert(ctx context.Context, alert *models.Alert) error {
 	if alert.ID == uuid.Nil { alert.ID = uuid.New() }
 	if alert.CreatedAt.IsZero() { alert.CreatedAt = time.Now() }
+	if alert.Kind == "" { alert.Kind = models.AlertKindPrice }
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO alerts (id, product_id, target_price, is_active, notification_type, created_at, notified_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, alert.ID.String(), alert.ProductID.String(), alert.TargetPrice, boolToInt(alert.IsActive), alert.NotificationType, alert.CreatedAt, nullTime(alert.NotifiedAt))
+		INSERT INTO alerts (`+alertColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, alert.ID.String(), alert.ProductID.String(), alert.TargetPrice, boolToInt(alert.IsActive), alert.NotificationType,
+		boolToInt(alert.UseTotalPrice), boolToInt(alert.SkipFakeDiscount), alert.Kind, alert.CreatedAt, nullTime(alert.NotifiedAt))
 	return err
 }
 
 // GetAlertByID implements Storage.GetAlertByID
 func (s *SQLiteStorage) GetAlertByID(ctx context.Context, id uuid.UUID) (*models.Alert, error) {
-	var a models.Alert
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, product_id, target_price, is_active, notification_type, created_at, notified_at
-		FROM alerts WHERE id = ?
-	`, id.String()).Scan(&a.ID, &a.ProductID, &a.TargetPrice, &a.IsActive, &a.NotificationType, &a.CreatedAt, &a.NotifiedAt)
+	a, err := scanAlert(s.db.QueryRowContext(ctx, `SELECT `+alertColumns+` FROM alerts WHERE id = ?`, id.String()))
 	if err == sql.ErrNoRows { return nil, nil }
 	if err != nil { return nil, err }
-	return &a, nil
+	return a, nil
 }
 
 // ListAlerts implements Storage.ListAlerts
 func (s *SQLiteStorage) ListAlerts(ctx context.Context, limit, offset int) ([]*models.Alert, error) {
-	query := `SELECT id, product_id, target_price, is_active, notification_type, created_at, notified_at FROM alerts ORDER BY created_at DESC`
+	query := `SELECT ` + alertColumns + ` FROM alerts ORDER BY created_at DESC`
 	args := []any{}
 	if limit > 0 { query += " LIMIT ?"; args = append(args, limit) }
 	if offset > 0 { query += " OFFSET ?"; args = append(args, offset) }
@@ -221,11 +580,11 @@ func (s *SQLiteStorage) ListAlerts(ctx context.Context, limit, offset int) ([]*m
 
 	var items []*models.Alert
 	for rows.Next() {
-		var a models.Alert
-		if err := rows.Scan(&a.ID, &a.ProductID, &a.TargetPrice, &a.IsActive, &a.NotificationType, &a.CreatedAt, &a.NotifiedAt); err != nil {
+		a, err := scanAlert(rows)
+		if err != nil {
 			return nil, err
 		}
-		items = append(items, &a)
+		items = append(items, a)
 	}
 	return items, rows.Err()
 }
@@ -233,7 +592,7 @@ func (s *SQLiteStorage) ListAlerts(ctx context.Context, limit, offset int) ([]*m
 // GetActiveAlertsForProduct implements Storage.GetActiveAlertsForProduct
 func (s *SQLiteStorage) GetActiveAlertsForProduct(ctx context.Context, productID uuid.UUID) ([]*models.Alert, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, product_id, target_price, is_active, notification_type, created_at, notified_at
+		SELECT `+alertColumns+`
 		FROM alerts WHERE product_id = ? AND is_active = 1
 	`, productID.String())
 	if err != nil { return nil, err }
@@ -241,11 +600,11 @@ func (s *SQLiteStorage) GetActiveAlertsForProduct(ctx context.Context, productID
 
 	var items []*models.Alert
 	for rows.Next() {
-		var a models.Alert
-		if err := rows.Scan(&a.ID, &a.ProductID, &a.TargetPrice, &a.IsActive, &a.NotificationType, &a.CreatedAt, &a.NotifiedAt); err != nil {
+		a, err := scanAlert(rows)
+		if err != nil {
 			return nil, err
 		}
-		items = append(items, &a)
+		items = append(items, a)
 	}
 	return items, rows.Err()
 }
@@ -253,12 +612,25 @@ func (s *SQLiteStorage) GetActiveAlertsForProduct(ctx context.Context, productID
 // UpdateAlert implements Storage.UpdateAlert
 func (s *SQLiteStorage) UpdateAlert(ctx context.Context, alert *models.Alert) error {
 	_, err := s.db.ExecContext(ctx, `
-		UPDATE alerts SET product_id = ?, target_price = ?, is_active = ?, notification_type = ?, created_at = ?, notified_at = ?
+		UPDATE alerts SET product_id = ?, target_price = ?, is_active = ?, notification_type = ?, use_total_price = ?, skip_fake_discount = ?, kind = ?, created_at = ?, notified_at = ?
 		WHERE id = ?
-	`, alert.ProductID.String(), alert.TargetPrice, boolToInt(alert.IsActive), alert.NotificationType, alert.CreatedAt, nullTime(alert.NotifiedAt), alert.ID.String())
+	`, alert.ProductID.String(), alert.TargetPrice, boolToInt(alert.IsActive), alert.NotificationType, boolToInt(alert.UseTotalPrice),
+		boolToInt(alert.SkipFakeDiscount), alert.Kind, alert.CreatedAt, nullTime(alert.NotifiedAt), alert.ID.String())
 	return err
 }
 
+// ClaimAlert implements Storage.ClaimAlert
+func (s *SQLiteStorage) ClaimAlert(ctx context.Context, id uuid.UUID, since, now time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE alerts SET notified_at = ?
+		WHERE id = ? AND is_active = 1 AND (notified_at IS NULL OR notified_at < ?)
+	`, now, id.String(), since)
+	if err != nil { return false, err }
+	n, err := res.RowsAffected()
+	if err != nil { return false, err }
+	return n > 0, nil
+}
+
 // DeleteAlert implements Storage.DeleteAlert
 func (s *SQLiteStorage) DeleteAlert(ctx context.Context, id uuid.UUID) error {
 	_, err := s.db.ExecContext(ctx, `DELETE FROM alerts WHERE id = ?`, id.String())
@@ -2,11 +2,17 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/PedroM2626/PriceWatcher/internal/models"
 )
 
+// ErrLeaseLost is returned by RenewLease and ReleaseJob when another worker
+// leased the job after the caller's lease expired
+var ErrLeaseLost = errors.New("scrape job lease lost")
+
 // Storage defines the interface for database operations
 // Implementations: SQLite (default). You can add Postgres or others by implementing this interface.
 type Storage interface {
@@ -17,16 +23,47 @@ type Storage interface {
 	ListProducts(ctx context.Context, limit, offset int) ([]*models.Product, error)
 	DeleteProduct(ctx context.Context, id uuid.UUID) error
 
+	// Scrape queue: CreateProduct enqueues a job for every product.
+	// LeaseJobs hands up to limit due jobs whose lease is free or expired to
+	// owner, counting an attempt; ReleaseJob ends owner's lease and sets the
+	// next run (and the product's next_check_at), resetting the attempts when
+	// lastError is empty. SetNextCheck moves a job without touching its
+	// lease; a zero time makes the product due right away.
+	LeaseJobs(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]*models.ScrapeJob, error)
+	RenewLease(ctx context.Context, productID uuid.UUID, owner string, until time.Time) error
+	ReleaseJob(ctx context.Context, productID uuid.UUID, owner string, nextRunAt time.Time, lastError string) error
+	SetNextCheck(ctx context.Context, productID uuid.UUID, at time.Time) error
+
+	// Site schedule operations; GetSiteSchedule returns nil when the host has none
+	SetSiteSchedule(ctx context.Context, schedule *models.SiteSchedule) error
+	GetSiteSchedule(ctx context.Context, host string) (*models.SiteSchedule, error)
+	ListSiteSchedules(ctx context.Context) ([]*models.SiteSchedule, error)
+	DeleteSiteSchedule(ctx context.Context, host string) error
+
 	// Price history operations
-	AddPriceHistory(ctx context.Context, productID uuid.UUID, price float64) error
+	AddPriceHistory(ctx context.Context, entry *models.PriceHistory) error
 	GetPriceHistory(ctx context.Context, productID uuid.UUID, sinceDays int) ([]*models.PriceHistory, error)
 
+	// Offer operations; the offers of one check share the same CreatedAt
+	AddOffers(ctx context.Context, productID uuid.UUID, offers []models.Offer) error
+	GetOffers(ctx context.Context, productID uuid.UUID, sinceDays int) ([]*models.Offer, error)
+	GetLatestOffers(ctx context.Context, productID uuid.UUID) ([]*models.Offer, error)
+
+	// Scrape run operations; runs are returned newest first
+	AddScrapeRun(ctx context.Context, run *models.ScrapeRun) error
+	GetScrapeRuns(ctx context.Context, productID uuid.UUID, limit int) ([]*models.ScrapeRun, error)
+
 	// Alert operations
 	CreateAlert(ctx context.Context, alert *models.Alert) error
 	GetAlertByID(ctx context.Context, id uuid.UUID) (*models.Alert, error)
 	ListAlerts(ctx context.Context, limit, offset int) ([]*models.Alert, error)
 	GetActiveAlertsForProduct(ctx context.Context, productID uuid.UUID) ([]*models.Alert, error)
 	UpdateAlert(ctx context.Context, alert *models.Alert) error
+	// ClaimAlert marks an active alert notified at now unless it was already
+	// notified at or after since, and reports whether the caller won it. With
+	// since set to the start of the check, overlapping checks of the same
+	// product on different instances notify once.
+	ClaimAlert(ctx context.Context, id uuid.UUID, since, now time.Time) (bool, error)
 	DeleteAlert(ctx context.Context, id uuid.UUID) error
 
 	// Close closes the database connection
@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PedroM2626/PriceWatcher/internal/models"
+	"github.com/google/uuid"
+)
+
+// postgresDSNEnv names the database the Postgres tests run against; they are
+// skipped when it is unset. The tests create and delete their own rows.
+const postgresDSNEnv = "PRICEWATCHER_TEST_POSTGRES_DSN"
+
+func newSQLiteTestStorage(t *testing.T) Storage {
+	t.Helper()
+	return openSQLiteTestStorage(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+// openSQLiteTestStorage opens the database at path, which may already be
+// open elsewhere in the test, as another replica would
+func openSQLiteTestStorage(t *testing.T, path string) Storage {
+	t.Helper()
+	s, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func newPostgresTestStorage(t *testing.T) Storage {
+	t.Helper()
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skip(postgresDSNEnv + " not set")
+	}
+	s, err := NewPostgresStorage(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteAlerts(t *testing.T) {
+	testAlerts(t, newSQLiteTestStorage(t))
+}
+
+func TestPostgresAlerts(t *testing.T) {
+	testAlerts(t, newPostgresTestStorage(t))
+}
+
+// testAlerts checks that UpdateAlert writes every field of the alert it is
+// given, and only that alert, and that ClaimAlert claims an alert only once
+// per period
+func testAlerts(t *testing.T, s Storage) {
+	ctx := context.Background()
+	product := &models.Product{
+		Name:     "Test product",
+		URL:      "https://store.example/p/" + uuid.NewString(),
+		Currency: "BRL",
+	}
+	if err := s.CreateProduct(ctx, product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	t.Cleanup(func() { s.DeleteProduct(ctx, product.ID) })
+
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	alert := &models.Alert{ProductID: product.ID, TargetPrice: 100, IsActive: true, NotificationType: "email", CreatedAt: created}
+	other := &models.Alert{ProductID: product.ID, TargetPrice: 50, IsActive: true, NotificationType: "email", CreatedAt: created}
+	for _, a := range []*models.Alert{alert, other} {
+		a := a
+		if err := s.CreateAlert(ctx, a); err != nil {
+			t.Fatalf("CreateAlert: %v", err)
+		}
+		t.Cleanup(func() { s.DeleteAlert(ctx, a.ID) })
+	}
+
+	notified := time.Now().Truncate(time.Second)
+	alert.TargetPrice = 80
+	alert.IsActive = false
+	alert.NotificationType = "telegram"
+	alert.UseTotalPrice = true
+	alert.SkipFakeDiscount = true
+	alert.Kind = models.AlertKindBackInStock
+	alert.NotifiedAt = notified
+	if err := s.UpdateAlert(ctx, alert); err != nil {
+		t.Fatalf("UpdateAlert: %v", err)
+	}
+
+	got, err := s.GetAlertByID(ctx, alert.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetAlertByID = %v, %v", got, err)
+	}
+	if got.TargetPrice != 80 || got.IsActive || got.NotificationType != "telegram" ||
+		!got.UseTotalPrice || !got.SkipFakeDiscount || got.Kind != models.AlertKindBackInStock {
+		t.Errorf("updated alert = %+v, want the new field values", got)
+	}
+	if !got.NotifiedAt.Equal(notified) || !got.CreatedAt.Equal(created) {
+		t.Errorf("NotifiedAt, CreatedAt = %v, %v, want %v, %v", got.NotifiedAt, got.CreatedAt, notified, created)
+	}
+
+	untouched, err := s.GetAlertByID(ctx, other.ID)
+	if err != nil || untouched == nil {
+		t.Fatalf("GetAlertByID = %v, %v", untouched, err)
+	}
+	if untouched.TargetPrice != 50 || !untouched.IsActive || !untouched.NotifiedAt.IsZero() {
+		t.Errorf("UpdateAlert changed another alert: %+v", untouched)
+	}
+
+	// other was never notified, so it can be claimed once per period
+	since := time.Now().Add(-24 * time.Hour)
+	for i, want := range []bool{true, false} {
+		ok, err := s.ClaimAlert(ctx, other.ID, since, time.Now())
+		if err != nil {
+			t.Fatalf("ClaimAlert: %v", err)
+		}
+		if ok != want {
+			t.Errorf("ClaimAlert #%d = %v, want %v", i+1, ok, want)
+		}
+	}
+	// alert is inactive
+	if ok, err := s.ClaimAlert(ctx, alert.ID, time.Now(), time.Now()); ok || err != nil {
+		t.Errorf("ClaimAlert on an inactive alert = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestSQLiteJobQueue(t *testing.T) {
+	testJobQueue(t, newSQLiteTestStorage(t))
+}
+
+func TestPostgresJobQueue(t *testing.T) {
+	testJobQueue(t, newPostgresTestStorage(t))
+}
+
+// testJobQueue checks that LeaseJobs hands out due, unleased jobs oldest
+// first, that RenewLease extends only the owner's lease, and that ReleaseJob
+// schedules the next run and counts failed attempts
+func testJobQueue(t *testing.T, s Storage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	var early, late, future uuid.UUID
+	for _, tt := range []struct {
+		id  *uuid.UUID
+		due time.Time
+	}{
+		{&late, now.Add(-time.Hour)},
+		{&early, now.Add(-2 * time.Hour)},
+		{&future, now.Add(time.Hour)},
+	} {
+		product := &models.Product{
+			Name:     "Test product",
+			URL:      "https://store.example/p/" + uuid.NewString(),
+			Currency: "BRL",
+		}
+		if err := s.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+		id := product.ID
+		t.Cleanup(func() { s.DeleteProduct(ctx, id) })
+		if err := s.SetNextCheck(ctx, id, tt.due); err != nil {
+			t.Fatalf("SetNextCheck: %v", err)
+		}
+		*tt.id = id
+	}
+
+	// lease returns our jobs among those leased at the given time
+	lease := func(at time.Time, limit int) map[uuid.UUID]*models.ScrapeJob {
+		t.Helper()
+		jobs, err := s.LeaseJobs(ctx, "worker", at, time.Minute, limit)
+		if err != nil {
+			t.Fatalf("LeaseJobs: %v", err)
+		}
+		ours := make(map[uuid.UUID]*models.ScrapeJob)
+		for _, job := range jobs {
+			if job.ProductID == early || job.ProductID == late || job.ProductID == future {
+				ours[job.ProductID] = job
+			}
+		}
+		return ours
+	}
+
+	jobs := lease(now, 1)
+	if len(jobs) != 1 || jobs[early] == nil {
+		t.Fatalf("LeaseJobs(limit 1) = %v, want the job due first", jobs)
+	}
+	if job := jobs[early]; job.LeaseOwner != "worker" || job.Attempts != 1 || !job.LeaseExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("leased job: owner %q, %d attempts, expires %v", job.LeaseOwner, job.Attempts, job.LeaseExpiresAt)
+	}
+	if jobs := lease(now, 10); len(jobs) != 1 || jobs[late] == nil {
+		t.Fatalf("LeaseJobs = %v, want only the other due job", jobs)
+	}
+
+	// Renewing keeps early's lease past late's
+	if err := s.RenewLease(ctx, early, "worker", now.Add(5*time.Minute)); err != nil {
+		t.Fatalf("RenewLease: %v", err)
+	}
+	if err := s.RenewLease(ctx, early, "intruder", now.Add(5*time.Minute)); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("RenewLease by another owner = %v, want ErrLeaseLost", err)
+	}
+	if jobs := lease(now.Add(2*time.Minute), 10); len(jobs) != 1 || jobs[late] == nil || jobs[late].Attempts != 2 {
+		t.Fatalf("LeaseJobs after late's lease expired = %v, want late on its second attempt", jobs)
+	}
+
+	// A failed check keeps counting attempts; a successful one resets them
+	if err := s.ReleaseJob(ctx, early, "worker", now.Add(-time.Minute), "timeout"); err != nil {
+		t.Fatalf("ReleaseJob: %v", err)
+	}
+	jobs = lease(now, 10)
+	if job := jobs[early]; job == nil || job.Attempts != 2 || job.LastError != "timeout" {
+		t.Fatalf("LeaseJobs after a failed check = %v, want early on its second attempt", jobs)
+	}
+	if err := s.ReleaseJob(ctx, early, "worker", now.Add(time.Hour), ""); err != nil {
+		t.Fatalf("ReleaseJob: %v", err)
+	}
+	if jobs := lease(now.Add(10*time.Minute), 10); jobs[early] != nil {
+		t.Errorf("LeaseJobs leased early before its next run")
+	}
+	product, err := s.GetProductByID(ctx, early)
+	if err != nil {
+		t.Fatalf("GetProductByID: %v", err)
+	}
+	if !product.NextCheckAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("NextCheckAt = %v, want %v", product.NextCheckAt, now.Add(time.Hour))
+	}
+	jobs = lease(now.Add(time.Hour), 10)
+	if job := jobs[early]; job == nil || job.Attempts != 1 {
+		t.Errorf("LeaseJobs at the next run = %v, want early on its first attempt", jobs)
+	}
+
+	if err := s.DeleteProduct(ctx, future); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+	if err := s.ReleaseJob(ctx, future, "worker", now, ""); err != nil {
+		t.Errorf("ReleaseJob of a deleted product = %v, want nil", err)
+	}
+}
+
+func TestSQLiteJobLeases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	testJobLeases(t, openSQLiteTestStorage(t, path), openSQLiteTestStorage(t, path))
+}
+
+func TestPostgresJobLeases(t *testing.T) {
+	testJobLeases(t, newPostgresTestStorage(t), newPostgresTestStorage(t))
+}
+
+// testJobLeases checks, through two replicas a and b of the same database,
+// that concurrent workers never lease the same job, and that a worker whose
+// lease expired and was taken over can neither renew nor release it
+func testJobLeases(t *testing.T, a, b Storage) {
+	ctx := context.Background()
+	const n = 50
+	ours := make(map[uuid.UUID]bool, n)
+	for i := 0; i < n; i++ {
+		product := &models.Product{
+			Name:     "Test product",
+			URL:      "https://store.example/p/" + uuid.NewString(),
+			Currency: "BRL",
+		}
+		if err := a.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+		ours[product.ID] = true
+		id := product.ID
+		t.Cleanup(func() { a.DeleteProduct(ctx, id) })
+	}
+
+	// Both replicas drain the queue one job at a time, at the same moment
+	now := time.Now()
+	leased := make([][]*models.ScrapeJob, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, s := range []Storage{a, b} {
+		i, s := i, s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			owner := fmt.Sprintf("worker-%d", i)
+			for {
+				jobs, err := s.LeaseJobs(ctx, owner, now, time.Minute, 1)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				if len(jobs) == 0 {
+					return
+				}
+				leased[i] = append(leased[i], jobs...)
+			}
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("worker-%d: LeaseJobs: %v", i, err)
+		}
+	}
+
+	seen := make(map[uuid.UUID]string)
+	for i, jobs := range leased {
+		owner := fmt.Sprintf("worker-%d", i)
+		for _, job := range jobs {
+			if prev, ok := seen[job.ProductID]; ok {
+				t.Errorf("job %s leased by both %s and %s", job.ProductID, prev, owner)
+			}
+			seen[job.ProductID] = owner
+			if job.LeaseOwner != owner || job.Attempts != 1 {
+				t.Errorf("job %s: owner %q, %d attempts, want %q, 1", job.ProductID, job.LeaseOwner, job.Attempts, owner)
+			}
+		}
+	}
+	for id := range ours {
+		if seen[id] == "" {
+			t.Errorf("job %s was never leased", id)
+		}
+	}
+
+	// Park every job but one, then let its owner's lease run out and the
+	// other replica take it over
+	replicas := map[string]Storage{"worker-0": a, "worker-1": b}
+	var job uuid.UUID
+	var former, current string
+	for id, owner := range seen {
+		if job == uuid.Nil && ours[id] {
+			job, former = id, owner
+			continue
+		}
+		if err := replicas[owner].ReleaseJob(ctx, id, owner, now.Add(time.Hour), ""); err != nil {
+			t.Fatalf("ReleaseJob: %v", err)
+		}
+	}
+	current = "worker-0"
+	if former == current {
+		current = "worker-1"
+	}
+
+	later := now.Add(2 * time.Minute)
+	jobs, err := replicas[current].LeaseJobs(ctx, current, later, time.Minute, n)
+	if err != nil {
+		t.Fatalf("LeaseJobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ProductID != job || jobs[0].Attempts != 2 {
+		t.Fatalf("LeaseJobs after expiry = %v, want only job %s on its second attempt", jobs, job)
+	}
+	if err := replicas[former].RenewLease(ctx, job, former, later.Add(time.Minute)); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("RenewLease by the former owner = %v, want ErrLeaseLost", err)
+	}
+	if err := replicas[former].ReleaseJob(ctx, job, former, later, "timeout"); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("ReleaseJob by the former owner = %v, want ErrLeaseLost", err)
+	}
+	if err := replicas[current].RenewLease(ctx, job, current, later.Add(2*time.Minute)); err != nil {
+		t.Errorf("RenewLease by the owner: %v", err)
+	}
+	if err := replicas[current].ReleaseJob(ctx, job, current, later.Add(time.Hour), ""); err != nil {
+		t.Errorf("ReleaseJob by the owner: %v", err)
+	}
+}
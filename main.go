@@ -8,9 +8,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/PedroM2626/PriceWatcher/internal/alerts"
 	"github.com/PedroM2626/PriceWatcher/internal/api"
 	"github.com/PedroM2626/PriceWatcher/internal/config"
 	"github.com/PedroM2626/PriceWatcher/internal/scraper"
+	"github.com/PedroM2626/PriceWatcher/internal/siterules"
 	"github.com/PedroM2626/PriceWatcher/internal/storage"
 )
 
@@ -28,7 +30,7 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize and start API server
+	// Initialize API server
 	apiCfg := api.Config{
 		Address:        ":8080",
 		Environment:    "development",
@@ -40,6 +42,22 @@ func main() {
 
 	server := api.NewServer(apiCfg, db)
 
+	// Load per-site extraction rules
+	rules, err := siterules.LoadDir(cfg.Scraper.RulesDir)
+	if err != nil {
+		log.Fatalf("Failed to load site rules: %v", err)
+	}
+
+	scraperCfg, err := scraper.ConfigFrom(cfg.Scraper, rules)
+	if err != nil {
+		log.Fatalf("Failed to configure scraper: %v", err)
+	}
+
+	// Initialize scraper
+	ps := scraper.NewScraper(db, scraperCfg)
+
+	server.SetScraperStats(ps)
+
 	// Start API server in a goroutine
 	go func() {
 		log.Printf("Starting API server on %s", apiCfg.Address)
@@ -48,18 +66,13 @@ func main() {
 		}
 	}()
 
-	// Initialize scraper
-	ps := scraper.NewScraper(db, scraper.ScraperConfig{
-		UserAgent:      cfg.Scraper.UserAgent,
-		RequestDelay:   cfg.Scraper.RequestDelay,
-		RequestTimeout: cfg.Scraper.RequestTimeout,
-		Workers:        cfg.Scraper.Workers,
-	})
-
-	// Start scraping in a separate goroutine
+	// Check due products and evaluate their alerts in a separate goroutine
+	scrapeCtx, stopScraper := context.WithCancel(context.Background())
+	scraperDone := make(chan struct{})
 	go func() {
+		defer close(scraperDone)
 		log.Println("Starting price scraper...")
-		if err := ps.Run(); err != nil {
+		if err := ps.Run(scrapeCtx, alerts.NewEvaluator(db).HandleCheck); err != nil {
 			log.Fatalf("Scraper error: %v", err)
 		}
 	}()
@@ -72,6 +85,9 @@ func main() {
 	log.Println("Shutting down PriceWatcher...")
 
 	// Graceful shutdown
+	stopScraper()
+	<-scraperDone
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
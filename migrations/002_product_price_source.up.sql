@@ -0,0 +1,2 @@
+-- Record which extraction strategy produced a product's current price
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price_source TEXT NOT NULL DEFAULT '';
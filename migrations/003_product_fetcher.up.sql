@@ -0,0 +1,2 @@
+-- Let a product override how its page is downloaded (direct, scraperapi, fixture)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS fetcher TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Outcome of the last check of each product (ok, failed, gone, disallowed)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS scrape_status TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- HTTP validators for conditional requests (If-None-Match / If-Modified-Since)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS etag TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS last_modified TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Variants found on the product page (JSON) and the one the product is pinned to
+ALTER TABLE products ADD COLUMN IF NOT EXISTS variants TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS variant_sku TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS variant_attributes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Seller offers of marketplace products, one row per seller per recorded check
+CREATE TABLE IF NOT EXISTS offers (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    seller TEXT NOT NULL DEFAULT '',
+    price DOUBLE PRECISION NOT NULL,
+    shipping DOUBLE PRECISION NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    condition TEXT NOT NULL DEFAULT '',
+    fulfillment TEXT NOT NULL DEFAULT '',
+    buy_box BOOLEAN NOT NULL DEFAULT FALSE,
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_offers_product_id_created_at ON offers(product_id, created_at);
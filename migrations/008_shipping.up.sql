@@ -0,0 +1,7 @@
+-- Shipping cost and free-shipping flags, and alerts on the landed (total) price
+ALTER TABLE products ADD COLUMN IF NOT EXISTS shipping_cost DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE price_history ADD COLUMN IF NOT EXISTS shipping DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE price_history ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS use_total_price BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Alerts can opt out of firing on discounts the price history doesn't back
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS skip_fake_discount BOOLEAN NOT NULL DEFAULT FALSE;
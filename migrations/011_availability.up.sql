@@ -0,0 +1,4 @@
+-- Availability states with transitions recorded in the price history, and back-in-stock alerts
+ALTER TABLE products ADD COLUMN IF NOT EXISTS availability TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE price_history ADD COLUMN IF NOT EXISTS availability TEXT NOT NULL DEFAULT '';
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'price';
@@ -0,0 +1,13 @@
+-- Outcome of every check of a product
+CREATE TABLE IF NOT EXISTS scrape_runs (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    price DOUBLE PRECISION NOT NULL DEFAULT 0,
+    availability TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_scrape_runs_product_id_started_at ON scrape_runs(product_id, started_at);
@@ -0,0 +1,10 @@
+-- Per-product and per-store check schedules
+ALTER TABLE products ADD COLUMN IF NOT EXISTS schedule TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS next_check_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_products_next_check_at ON products(next_check_at);
+
+CREATE TABLE IF NOT EXISTS site_schedules (
+    host TEXT PRIMARY KEY,
+    schedule TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,15 @@
+-- Persistent scrape queue: one job per product, leased by workers
+CREATE TABLE IF NOT EXISTS scrape_jobs (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    lease_owner TEXT NOT NULL DEFAULT '',
+    lease_expires_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_scrape_jobs_next_run_at ON scrape_jobs(next_run_at);
+
+INSERT INTO scrape_jobs (product_id, next_run_at, updated_at)
+SELECT id, COALESCE(next_check_at, NOW()), NOW() FROM products
+ON CONFLICT (product_id) DO NOTHING;